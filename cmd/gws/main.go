@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/fs0414/git-worktree-sync/internal/cli"
+	"github.com/fs0414/git-worktree-sync/internal/sync"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +16,8 @@ var (
 )
 
 func main() {
+	sync.Version = version
+
 	rootCmd := &cobra.Command{
 		Use:   "gws",
 		Short: "Git worktree resource synchronization tool",
@@ -30,6 +33,8 @@ and other dependencies from your main worktree.`,
 	rootCmd.AddCommand(cli.InitCmd())
 	rootCmd.AddCommand(cli.SyncCmd())
 	rootCmd.AddCommand(cli.ListCmd())
+	rootCmd.AddCommand(cli.RemoveCmd())
+	rootCmd.AddCommand(cli.ConfigCmd())
 
 	// Execute
 	if err := rootCmd.Execute(); err != nil {
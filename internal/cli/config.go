@@ -0,0 +1,480 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+	"github.com/fs0414/git-worktree-sync/internal/git"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigCmd creates the 'config' command
+func ConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and edit gws configuration",
+		Long: `Inspect the effective gws configuration and edit configuration files.
+
+Configuration is layered: built-in defaults, the global config
+(~/.config/gws/config.yml), the project's .gwt.yml and the git-ignored
+.gwt.local.yml, each overriding the previous ones.
+
+Keys are dotted paths: worktree_path, merge, package_manager,
+resources.symlink, resources.copy, exclude and hooks.<name>. Edits preserve comments and key
+order of the file.`,
+	}
+
+	cmd.AddCommand(configShowCmd())
+	cmd.AddCommand(configGetCmd())
+	cmd.AddCommand(configSetCmd())
+	cmd.AddCommand(configAddCmd())
+	cmd.AddCommand(configRemoveCmd())
+	cmd.AddCommand(configValidateCmd())
+
+	return cmd
+}
+
+// configTarget selects the config file edited by a config subcommand
+type configTarget struct {
+	global bool
+	local  bool
+}
+
+func (t *configTarget) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&t.global, "global", false, "Use the global config (~/.config/gws/config.yml)")
+	cmd.Flags().BoolVar(&t.local, "local", false, "Use the local config (.gwt.local.yml)")
+	cmd.MarkFlagsMutuallyExclusive("global", "local")
+}
+
+// path returns the config file selected by the flags
+func (t *configTarget) path() (string, error) {
+	if t.global {
+		return config.GlobalConfigPath()
+	}
+
+	mainPath, err := mainWorktreePath()
+	if err != nil {
+		return "", err
+	}
+	if t.local {
+		return filepath.Join(mainPath, config.LocalConfigFileName), nil
+	}
+	return filepath.Join(mainPath, config.ConfigFileName), nil
+}
+
+// edit opens the selected config file, applies fn and saves it, then prints
+// the message fn returns. Edits that leave the file invalid are not saved.
+func (t *configTarget) edit(fn func(doc *config.Document) (string, error)) error {
+	path, err := t.path()
+	if err != nil {
+		return err
+	}
+
+	doc, err := config.OpenDocument(path)
+	if err != nil {
+		return err
+	}
+	message, err := fn(doc)
+	if err != nil {
+		return err
+	}
+	if err := doc.Validate(); err != nil {
+		return fmt.Errorf("edit not saved: %w", err)
+	}
+	if err := doc.Save(); err != nil {
+		return err
+	}
+	fmt.Println(message)
+	return nil
+}
+
+func configGetCmd() *cobra.Command {
+	var target configTarget
+
+	cmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a configuration value",
+		Long: `Print a configuration value. Lists are printed one item per line.
+Without --global or --local the effective value from all layers is printed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigGet(&target, args[0])
+		},
+	}
+
+	target.addFlags(cmd)
+
+	return cmd
+}
+
+func runConfigGet(target *configTarget, key string) error {
+	var doc *config.Document
+	if target.global || target.local {
+		path, err := target.path()
+		if err != nil {
+			return err
+		}
+		doc, err = config.OpenDocument(path)
+		if err != nil {
+			return err
+		}
+	} else {
+		mainPath, err := mainWorktreePath()
+		if err != nil {
+			return err
+		}
+		layered, err := config.LoadLayered(mainPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		doc, err = config.DocumentFromConfig(layered.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	values, ok, err := doc.Get(key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	for _, value := range values {
+		fmt.Println(value)
+	}
+	return nil
+}
+
+func configSetCmd() *cobra.Command {
+	var target configTarget
+
+	cmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a configuration value",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return target.edit(func(doc *config.Document) (string, error) {
+				if err := doc.Set(args[0], args[1]); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("✓ Set %s in %s", args[0], doc.Path()), nil
+			})
+		},
+	}
+
+	target.addFlags(cmd)
+
+	return cmd
+}
+
+func configAddCmd() *cobra.Command {
+	var target configTarget
+
+	cmd := &cobra.Command{
+		Use:   "add <key> <value>",
+		Short: "Add a value to a configuration list",
+		Example: `  gws config add resources.symlink .venv
+  gws config add exclude "*.pyc" --global`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return target.edit(func(doc *config.Document) (string, error) {
+				added, err := doc.Add(args[0], args[1])
+				if err != nil {
+					return "", err
+				}
+				if !added {
+					return fmt.Sprintf("%s already contains %s", args[0], args[1]), nil
+				}
+				return fmt.Sprintf("✓ Added %s to %s in %s", args[1], args[0], doc.Path()), nil
+			})
+		},
+	}
+
+	target.addFlags(cmd)
+
+	return cmd
+}
+
+func configRemoveCmd() *cobra.Command {
+	var target configTarget
+
+	cmd := &cobra.Command{
+		Use:   "remove <key> [value]",
+		Short: "Remove a value from a configuration list, or unset a key",
+		Example: `  gws config remove resources.symlink .venv
+  gws config remove hooks.post_create`,
+		Aliases: []string{"rm"},
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value := ""
+			if len(args) > 1 {
+				value = args[1]
+			}
+			return target.edit(func(doc *config.Document) (string, error) {
+				if err := doc.Remove(args[0], value); err != nil {
+					return "", err
+				}
+				if value != "" {
+					return fmt.Sprintf("✓ Removed %s from %s in %s", value, args[0], doc.Path()), nil
+				}
+				return fmt.Sprintf("✓ Unset %s in %s", args[0], doc.Path()), nil
+			})
+		},
+	}
+
+	target.addFlags(cmd)
+
+	return cmd
+}
+
+func configValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [file...]",
+		Short: "Check configuration files for errors",
+		Long: `Check configuration files for unknown keys, wrongly typed values, resources
+that escape the worktree, duplicate resources and invalid exclude patterns.
+
+Without arguments every config layer that exists is checked.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigValidate(args)
+		},
+	}
+
+	return cmd
+}
+
+func runConfigValidate(files []string) error {
+	if len(files) == 0 {
+		mainPath, err := mainWorktreePath()
+		if err != nil {
+			return err
+		}
+		for _, path := range config.LayerPaths(mainPath) {
+			if _, err := os.Stat(path); err == nil {
+				files = append(files, path)
+			}
+		}
+		if len(files) == 0 {
+			fmt.Println("No configuration files found")
+			return nil
+		}
+	}
+
+	invalid := 0
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		err = config.Validate(path, data)
+		if err == nil {
+			fmt.Printf("✓ %s\n", path)
+			continue
+		}
+
+		invalid++
+		var problems config.ValidationErrors
+		if !errors.As(err, &problems) {
+			fmt.Printf("✗ %v\n", err)
+			continue
+		}
+		fmt.Printf("✗ %s\n", path)
+		for _, problem := range problems {
+			fmt.Printf("  %d:%d: %s\n", problem.Line, problem.Column, problem.Message)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d config files are invalid", invalid, len(files))
+	}
+	return nil
+}
+
+func configShowCmd() *cobra.Command {
+	var showOrigin bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the effective configuration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigShow(showOrigin)
+		},
+	}
+
+	cmd.Flags().BoolVar(&showOrigin, "origin", false, "Show which file each value comes from")
+
+	return cmd
+}
+
+// mainWorktreePath returns the main worktree of the repository containing the current directory
+func mainWorktreePath() (string, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	if !git.IsGitRepository(currentDir) {
+		return "", fmt.Errorf("not a git repository")
+	}
+
+	mainPath, err := git.GetWorktreeMainPath(currentDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get main worktree path: %w", err)
+	}
+
+	return mainPath, nil
+}
+
+func runConfigShow(showOrigin bool) error {
+	mainPath, err := mainWorktreePath()
+	if err != nil {
+		return err
+	}
+
+	layered, err := config.LoadLayered(mainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !showOrigin {
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(layered.Config); err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return encoder.Close()
+	}
+
+	printConfigWithOrigins(layered, mainPath)
+	return nil
+}
+
+// printConfigWithOrigins prints the effective config as YAML with each
+// value annotated with the file it came from
+func printConfigWithOrigins(layered *config.Layered, mainPath string) {
+	cfg := layered.Config
+
+	type line struct {
+		text   string
+		origin string
+	}
+	var lines []line
+
+	add := func(text, key string) {
+		l := line{text: text}
+		if key != "" {
+			l.origin = displayOrigin(layered.Origin(key), mainPath)
+		}
+		lines = append(lines, l)
+	}
+	list := func(indent, key string, items []string) {
+		for _, item := range items {
+			add(indent+"- "+yamlScalar(item), key+"["+item+"]")
+		}
+	}
+
+	add("resources:", "")
+	add("  symlink:", "")
+	list("    ", "resources.symlink", cfg.Resources.Symlink)
+	add("  copy:", "")
+	list("    ", "resources.copy", cfg.Resources.Copy)
+	add("worktree_path: "+yamlScalar(cfg.WorktreePath), "worktree_path")
+	add("exclude:", "")
+	list("  ", "exclude", cfg.Exclude)
+	if cfg.PackageManager != "" {
+		add("package_manager: "+yamlScalar(cfg.PackageManager), "package_manager")
+	}
+
+	if len(cfg.Hooks) > 0 {
+		names := make([]string, 0, len(cfg.Hooks))
+		for name := range cfg.Hooks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		add("hooks:", "")
+		for _, name := range names {
+			add("  "+name+": "+yamlScalar(cfg.Hooks[name]), "hooks."+name)
+		}
+	}
+
+	if len(cfg.ResourceOptions) > 0 {
+		resources := make([]string, 0, len(cfg.ResourceOptions))
+		for resource := range cfg.ResourceOptions {
+			resources = append(resources, resource)
+		}
+		sort.Strings(resources)
+
+		add("resource_options:", "")
+		for _, resource := range resources {
+			opts := cfg.ResourceOptions[resource]
+			add("  "+yamlScalar(resource)+":", "resource_options."+resource)
+			if len(opts.Lockfiles) > 0 {
+				items := make([]string, len(opts.Lockfiles))
+				for i, lockfile := range opts.Lockfiles {
+					items[i] = yamlScalar(lockfile)
+				}
+				add("    lockfiles: ["+strings.Join(items, ", ")+"]", "")
+			}
+			if opts.OnLockfileChange != "" {
+				add("    on_lockfile_change: "+yamlScalar(opts.OnLockfileChange), "")
+			}
+			if opts.Install != "" {
+				add("    install: "+yamlScalar(opts.Install), "")
+			}
+			if opts.CopyStrategy != "" {
+				add("    copy_strategy: "+yamlScalar(opts.CopyStrategy), "")
+			}
+		}
+	}
+
+	// Align the origin comments
+	width := 0
+	for _, l := range lines {
+		if l.origin != "" && len(l.text) > width {
+			width = len(l.text)
+		}
+	}
+	for _, l := range lines {
+		if l.origin == "" {
+			fmt.Println(l.text)
+		} else {
+			fmt.Printf("%-*s  # %s\n", width, l.text, l.origin)
+		}
+	}
+}
+
+// displayOrigin shortens a config file path for display
+func displayOrigin(origin, mainPath string) string {
+	if origin == "" || origin == config.OriginDefault {
+		return config.OriginDefault
+	}
+	if rel, err := filepath.Rel(mainPath, origin); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(homeDir, origin); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join("~", rel)
+		}
+	}
+	return origin
+}
+
+// yamlScalar formats a string as a YAML scalar, quoting it if necessary
+func yamlScalar(value string) string {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
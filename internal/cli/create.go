@@ -1,12 +1,14 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/fs0414/git-worktree-sync/internal/config"
 	"github.com/fs0414/git-worktree-sync/internal/git"
+	"github.com/fs0414/git-worktree-sync/internal/hooks"
 	"github.com/fs0414/git-worktree-sync/internal/sync"
 	"github.com/spf13/cobra"
 )
@@ -14,33 +16,197 @@ import (
 // CreateCmd creates the 'create' command
 func CreateCmd() *cobra.Command {
 	var (
-		path       string
-		copyMode   bool
-		noSync     bool
-		baseBranch string
+		path          string
+		copyMode      bool
+		noSync        bool
+		baseBranch    string
+		detach        string
+		jobs          int
+		keepOnFailure bool
+		dry           dryRunFlags
 	)
 
 	cmd := &cobra.Command{
 		Use:   "create <branch-name>",
 		Short: "Create a new worktree with resource synchronization",
 		Long: `Create a new git worktree and synchronize resources from the main worktree.
-Resources to sync are defined in .gwt.yml configuration file.`,
-		Args: cobra.ExactArgs(1),
+Resources to sync are defined in .gwt.yml configuration file.
+
+If the branch already exists locally it is checked out in the new worktree.
+If it only exists on a remote (e.g. origin/feature-x), a local branch
+tracking it is created. With --detach, the worktree is created with a
+detached HEAD at the given commit and the branch name is only used for
+the worktree path.
+
+If syncing resources, an install command or the post_create hook fails, the
+synced resources are rolled back, the worktree is removed and a branch
+created for it is deleted. Use --keep-on-failure to keep them for debugging.
+
+With --dry-run, the git command, the action planned for every resource and
+the hooks and install commands that would run are shown without changing
+anything.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if detach != "" {
+				return cobra.MaximumNArgs(1)(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			branchName := args[0]
-			return runCreate(branchName, path, baseBranch, copyMode, noSync)
+			if jobs < 0 {
+				return fmt.Errorf("--jobs must not be negative")
+			}
+			if err := dry.validate(); err != nil {
+				return err
+			}
+
+			branchName := detach
+			if len(args) > 0 {
+				branchName = args[0]
+			}
+			return runCreate(branchName, path, baseBranch, detach, copyMode, noSync, keepOnFailure, jobs, dry)
 		},
 	}
 
-	cmd.Flags().StringVarP(&path, "path", "p", "", "Worktree path (default: ../<branch-name>)")
+	cmd.Flags().StringVarP(&path, "path", "p", "", "Worktree path (default: worktree_path from .gwt.yml)")
 	cmd.Flags().BoolVarP(&copyMode, "copy", "c", false, "Use copy mode instead of symlink")
 	cmd.Flags().BoolVar(&noSync, "no-sync", false, "Skip resource synchronization")
 	cmd.Flags().StringVarP(&baseBranch, "base", "b", "", "Base branch for new branch")
+	cmd.Flags().StringVar(&detach, "detach", "", "Create the worktree with a detached HEAD at the given commit")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "Number of resources and files to sync in parallel (default: number of CPUs)")
+	cmd.Flags().BoolVar(&keepOnFailure, "keep-on-failure", false, "Keep the worktree and branch if setting up the worktree fails")
+	dry.addFlags(cmd)
+	cmd.MarkFlagsMutuallyExclusive("base", "detach")
 
 	return cmd
 }
 
-func runCreate(branchName, path, baseBranch string, copyMode, noSync bool) error {
+// checkout describes how the new worktree's HEAD is set up
+type checkout struct {
+	// branch is the local branch checked out in the worktree, empty if detached
+	branch string
+	// newBranch is set when the branch is created by gws
+	newBranch bool
+	// remoteBranch is the remote branch a new local branch tracks
+	remoteBranch string
+	// commit is the commit checked out with a detached HEAD
+	commit string
+}
+
+// resolveCheckout decides whether to create a new branch, check out an
+// existing local branch, track a remote branch or detach HEAD
+func resolveCheckout(branchName, baseBranch, detach string) (checkout, error) {
+	if detach != "" {
+		return checkout{commit: detach}, nil
+	}
+
+	if git.LocalBranchExists(branchName) {
+		if baseBranch != "" {
+			return checkout{}, fmt.Errorf("branch '%s' already exists; --base only applies to new branches", branchName)
+		}
+		return checkout{branch: branchName}, nil
+	}
+
+	remoteBranch, localName, err := git.FindRemoteBranch(branchName)
+	if err != nil {
+		return checkout{}, err
+	}
+	if remoteBranch != "" {
+		if baseBranch != "" {
+			return checkout{}, fmt.Errorf("branch '%s' exists on remote; --base only applies to new branches", branchName)
+		}
+		// origin/feature-x may refer to an existing local feature-x
+		if git.LocalBranchExists(localName) {
+			return checkout{branch: localName}, nil
+		}
+		return checkout{branch: localName, newBranch: true, remoteBranch: remoteBranch}, nil
+	}
+
+	return checkout{branch: branchName, newBranch: true}, nil
+}
+
+// args returns the git arguments that create the worktree at path
+func (c checkout) args(path, baseBranch string) []string {
+	switch {
+	case c.commit != "":
+		return git.DetachedWorktreeArgs(path, c.commit)
+	case c.remoteBranch != "":
+		return git.TrackWorktreeArgs(c.branch, path, c.remoteBranch)
+	case c.newBranch:
+		return git.CreateWorktreeArgs(c.branch, path, baseBranch)
+	default:
+		return git.CheckoutWorktreeArgs(c.branch, path)
+	}
+}
+
+// add creates the worktree at path
+func (c checkout) add(path, baseBranch string) error {
+	return git.AddWorktree(path, c.args(path, baseBranch))
+}
+
+// rev returns the commit the worktree checks out
+func (c checkout) rev(baseBranch string) string {
+	switch {
+	case c.commit != "":
+		return c.commit
+	case c.remoteBranch != "":
+		return c.remoteBranch
+	case c.newBranch && baseBranch != "":
+		return baseBranch
+	case c.newBranch:
+		return "HEAD"
+	default:
+		return c.branch
+	}
+}
+
+// describe returns a short human-readable description of the checkout
+func (c checkout) describe() string {
+	switch {
+	case c.commit != "":
+		return fmt.Sprintf("detached HEAD at %s", c.commit)
+	case c.remoteBranch != "":
+		return fmt.Sprintf("new branch %s tracking %s", c.branch, c.remoteBranch)
+	case c.newBranch:
+		return fmt.Sprintf("new branch %s", c.branch)
+	default:
+		return fmt.Sprintf("existing branch %s", c.branch)
+	}
+}
+
+// rollback undoes a failed create: the synced resources are restored, the
+// worktree at path is removed and the branch is deleted if it was created
+// for the worktree. It returns the outcome of every step.
+func (c checkout) rollback(path string, tx *sync.Transaction, err error) []sync.RollbackStep {
+	// An atomic sync has already rolled itself back
+	var steps []sync.RollbackStep
+	var rollbackErr *sync.RollbackError
+	if errors.As(err, &rollbackErr) {
+		steps = rollbackErr.Steps
+	} else {
+		steps = tx.Rollback()
+	}
+
+	removeErr := git.RemoveWorktree(path, true)
+	if removeErr == nil {
+		removeErr = git.PruneWorktrees()
+	}
+	steps = append(steps, sync.RollbackStep{
+		Description: fmt.Sprintf("Removed worktree at %s", path),
+		Error:       removeErr,
+	})
+
+	// The branch is still checked out if the worktree could not be removed
+	if c.newBranch && removeErr == nil {
+		steps = append(steps, sync.RollbackStep{
+			Description: fmt.Sprintf("Deleted branch %s", c.branch),
+			Error:       git.DeleteBranch(c.branch, true),
+		})
+	}
+
+	return steps
+}
+
+func runCreate(branchName, path, baseBranch, detach string, copyMode, noSync, keepOnFailure bool, jobs int, dry dryRunFlags) error {
 	// Check if we're in a git repository
 	currentDir, err := os.Getwd()
 	if err != nil {
@@ -51,74 +217,136 @@ func runCreate(branchName, path, baseBranch string, copyMode, noSync bool) error
 		return fmt.Errorf("not a git repository")
 	}
 
-	// Check if branch already exists
-	if git.BranchExists(branchName) {
-		return fmt.Errorf("branch '%s' already exists", branchName)
+	// Decide how the branch is checked out
+	co, err := resolveCheckout(branchName, baseBranch, detach)
+	if err != nil {
+		return err
+	}
+	if co.branch != "" {
+		branchName = co.branch
+	}
+
+	// Resources, config and relative worktree paths are taken from the main worktree
+	mainPath, err := git.GetWorktreeMainPath(currentDir)
+	if err != nil {
+		return fmt.Errorf("failed to get main worktree path: %w", err)
 	}
 
 	// Load config
-	var cfg *config.Config
-	if config.Exists(currentDir) {
-		cfg, err = config.Load(currentDir)
-		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
-		}
-	} else {
-		fmt.Println("⚠️  No .gwt.yml found, using default configuration")
-		fmt.Println("   Run 'gws init' to create a configuration file")
-		cfg = config.GetDefaultConfig()
+	layered, err := config.LoadLayered(mainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !layered.HasProjectConfig() {
+		dry.notice("⚠️  No .gwt.yml found, using default configuration\n")
+		dry.notice("   Run 'gws init' to create a configuration file\n")
 	}
+	cfg := layered.Config
 
 	// Resolve worktree path
 	var worktreePath string
 	if path != "" {
-		worktreePath = path
+		worktreePath, err = filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		if err := config.ValidateWorktreePath(mainPath, worktreePath); err != nil {
+			return err
+		}
 	} else {
-		worktreePath = cfg.ResolveWorktreePath(branchName)
+		worktreePath, err = cfg.ResolveWorktreePath(mainPath, branchName)
+		if err != nil {
+			return fmt.Errorf("failed to resolve worktree path: %w", err)
+		}
+	}
+
+	hookEnv := hooks.Env{
+		Branch:       co.branch,
+		WorktreePath: worktreePath,
+		MainPath:     mainPath,
+	}
+
+	if dry.enabled {
+		if err := git.CheckWorktreePath(worktreePath); err != nil {
+			return err
+		}
+
+		d := newDryRun("create", mainPath, worktreePath)
+		d.hook(cfg, hooks.PreCreate)
+		d.git(co.args(worktreePath, baseBranch))
+		if !noSync {
+			// The worktree does not exist yet, so its lockfiles are read
+			// from the commit it will check out
+			plans, err := sync.PlanSync(cfg, mainPath, worktreePath, sync.Options{
+				Copy: copyMode,
+				Rev:  co.rev(baseBranch),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to plan sync: %w", err)
+			}
+			d.resources(plans)
+		}
+		d.hook(cfg, hooks.PostCreate)
+		return d.print(dry.json)
 	}
 
-	// Make path absolute if it's relative
-	if !filepath.IsAbs(worktreePath) {
-		worktreePath = filepath.Join(filepath.Dir(currentDir), filepath.Base(worktreePath))
+	// Run pre_create hook before touching anything
+	if err := hooks.Run(cfg, hooks.PreCreate, hookEnv); err != nil {
+		return err
 	}
 
 	// Create worktree
-	fmt.Printf("Creating worktree at %s...\n", worktreePath)
-	if err := git.CreateWorktree(branchName, worktreePath, baseBranch); err != nil {
+	fmt.Printf("Creating worktree at %s (%s)...\n", worktreePath, co.describe())
+	if err := co.add(worktreePath, baseBranch); err != nil {
 		return err
 	}
 	fmt.Println("✓ Created worktree")
 
+	// From here on a failure undoes everything created so far
+	tx := sync.NewTransaction()
+	if err := setUpWorktree(cfg, mainPath, hookEnv, sync.Options{
+		Copy:        copyMode,
+		Jobs:        jobs,
+		Progress:    newProgress(),
+		Atomic:      !keepOnFailure,
+		Transaction: tx,
+	}, noSync); err != nil {
+		if keepOnFailure {
+			fmt.Printf("\n⚠️  Keeping worktree at %s (--keep-on-failure)\n", worktreePath)
+			return err
+		}
+		fmt.Println("\nRolling back changes...")
+		printRollback(co.rollback(worktreePath, tx, err))
+		return err
+	}
+
+	fmt.Printf("\n✨ Done! Run: cd %s\n", worktreePath)
+	return nil
+}
+
+// setUpWorktree syncs resources into the new worktree, runs the install
+// commands they request and the post_create hook
+func setUpWorktree(cfg *config.Config, mainPath string, hookEnv hooks.Env, opts sync.Options, noSync bool) error {
 	// Sync resources if not disabled
 	if !noSync {
 		fmt.Println("\nSynchronizing resources...")
-		results, err := sync.SyncResources(cfg, currentDir, worktreePath, copyMode)
+		results, err := sync.SyncResources(cfg, mainPath, hookEnv.WorktreePath, opts)
+		var rollbackErr *sync.RollbackError
+		if errors.As(err, &rollbackErr) {
+			printSyncResults(results)
+			return err
+		}
 		if err != nil {
 			return fmt.Errorf("failed to sync resources: %w", err)
 		}
 
-		// Display sync results
-		for _, result := range results {
-			if result.Mode == "skip" {
-				fmt.Printf("⚠️  Skipped %s (not found in source)\n", result.Resource)
-				continue
-			}
-			if result.Mode == "exists" {
-				continue // Skip already existing resources
-			}
-			if !result.Success {
-				fmt.Printf("✗ Failed to sync %s: %v\n", result.Resource, result.Error)
-				continue
-			}
+		printSyncResults(results)
 
-			if result.Mode == "symlink" {
-				fmt.Printf("✓ Linked %s\n", result.Resource)
-			} else if result.Mode == "copy" {
-				fmt.Printf("✓ Copied %s\n", result.Resource)
-			}
+		if err := runInstalls(results, hookEnv); err != nil {
+			return err
 		}
 	}
 
-	fmt.Printf("\n✨ Done! Run: cd %s\n", worktreePath)
-	return nil
+	// Run post_create hook in the new worktree
+	return hooks.Run(cfg, hooks.PostCreate, hookEnv)
 }
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+	"github.com/fs0414/git-worktree-sync/internal/git"
+	"github.com/fs0414/git-worktree-sync/internal/hooks"
+	"github.com/fs0414/git-worktree-sync/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// dryRunFlags holds the --dry-run and --json flags of commands that change
+// worktrees
+type dryRunFlags struct {
+	enabled bool
+	json    bool
+}
+
+func (f *dryRunFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.enabled, "dry-run", false, "Show what would be done without changing anything")
+	cmd.Flags().BoolVar(&f.json, "json", false, "Output the dry run as JSON")
+}
+
+func (f *dryRunFlags) validate() error {
+	if f.json && !f.enabled {
+		return fmt.Errorf("--json requires --dry-run")
+	}
+	return nil
+}
+
+// notice prints a message that is not part of the dry run's JSON output
+func (f *dryRunFlags) notice(format string, args ...any) {
+	if f.json {
+		fmt.Fprintf(os.Stderr, format, args...)
+	} else {
+		fmt.Printf(format, args...)
+	}
+}
+
+// dryRunSchemaVersion is bumped whenever the JSON output changes incompatibly
+const dryRunSchemaVersion = 1
+
+// dryRun is the plan of a command run with --dry-run, emitted as JSON with --json
+type dryRun struct {
+	SchemaVersion int        `json:"schema_version"`
+	Command       string     `json:"command"`
+	Worktree      string     `json:"worktree"`
+	MainWorktree  string     `json:"main_worktree"`
+	Steps         []planStep `json:"steps"`
+}
+
+// planStep is a single step of a dry run, in the order it would be taken
+type planStep struct {
+	// Kind is hook, git, resource, install or unlink
+	Kind string `json:"kind"`
+	// Hook is the name of a hook
+	Hook string `json:"hook,omitempty"`
+	// Command is the command line of a hook, install or git command
+	Command string `json:"command,omitempty"`
+	// Args are the arguments of a git command
+	Args []string `json:"args,omitempty"`
+	// Resource is the resource installed or unlinked
+	Resource string             `json:"resource,omitempty"`
+	Plan     *sync.ResourcePlan `json:"plan,omitempty"`
+}
+
+func newDryRun(command, mainPath, worktreePath string) *dryRun {
+	return &dryRun{
+		SchemaVersion: dryRunSchemaVersion,
+		Command:       command,
+		Worktree:      worktreePath,
+		MainWorktree:  mainPath,
+		Steps:         []planStep{},
+	}
+}
+
+// hook adds the named hook if it is defined
+func (d *dryRun) hook(cfg *config.Config, name string) {
+	if command, ok := hooks.Command(cfg, name); ok {
+		d.Steps = append(d.Steps, planStep{Kind: "hook", Hook: name, Command: command})
+	}
+}
+
+// git adds a git command
+func (d *dryRun) git(args []string) {
+	d.Steps = append(d.Steps, planStep{
+		Kind:    "git",
+		Command: git.FormatCommand(args),
+		Args:    append([]string{"git"}, args...),
+	})
+}
+
+// resources adds the resource plans followed by the install commands they
+// request, as runInstalls runs them after the sync
+func (d *dryRun) resources(plans []sync.ResourcePlan) {
+	for i := range plans {
+		d.Steps = append(d.Steps, planStep{Kind: "resource", Resource: plans[i].Resource, Plan: &plans[i]})
+	}
+	for _, plan := range plans {
+		if plan.Install != "" {
+			d.Steps = append(d.Steps, planStep{Kind: "install", Resource: plan.Resource, Command: plan.Install})
+		}
+	}
+}
+
+// unlink adds the removal of symlinked resources
+func (d *dryRun) unlink(resources []string) {
+	for _, resource := range resources {
+		d.Steps = append(d.Steps, planStep{Kind: "unlink", Resource: resource})
+	}
+}
+
+// print displays the plan, as JSON if jsonOutput is set
+func (d *dryRun) print(jsonOutput bool) error {
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	}
+
+	fmt.Printf("🔍 Dry run of gws %s for %s (nothing is changed)\n\n", d.Command, d.Worktree)
+	if len(d.Steps) == 0 {
+		fmt.Println("Nothing to do")
+		return nil
+	}
+	for _, step := range d.Steps {
+		switch step.Kind {
+		case "hook":
+			fmt.Printf("▶ %s hook: %s\n", step.Hook, step.Command)
+		case "git":
+			fmt.Printf("$ %s\n", step.Command)
+		case "install":
+			fmt.Printf("▶ install for %s: %s\n", step.Resource, step.Command)
+		case "unlink":
+			fmt.Printf("  %-8s %s\n", "unlink", step.Resource)
+		case "resource":
+			printResourcePlan(step.Plan)
+		}
+	}
+	return nil
+}
+
+// printResourcePlan displays the action planned for a resource and the
+// paths excluded from it
+func printResourcePlan(plan *sync.ResourcePlan) {
+	detail := plan.Reason
+	switch plan.Action {
+	case sync.ActionCopy:
+		detail = describeFiles(plan.Files, plan.Bytes)
+	case sync.ActionReplace:
+		detail = "existing destination is backed up, then replaced by a " + plan.Mode
+		if plan.Mode == "copy" {
+			detail += " of " + describeFiles(plan.Files, plan.Bytes)
+		}
+	case sync.ActionExists:
+		detail = "already exists"
+	}
+	if plan.Unlink {
+		if detail != "" {
+			detail += "; "
+		}
+		detail += "the link to the main worktree is removed first"
+	}
+
+	if detail != "" {
+		fmt.Printf("  %-8s %s (%s)\n", plan.Action, plan.Resource, detail)
+	} else {
+		fmt.Printf("  %-8s %s\n", plan.Action, plan.Resource)
+	}
+	for _, path := range plan.Excluded {
+		fmt.Printf("  %-8s %s\n", "exclude", path)
+	}
+	if plan.Warning != "" {
+		fmt.Printf("  ⚠️  %s: %s\n", plan.Resource, plan.Warning)
+	}
+}
+
+// describeFiles formats the number and size of copied files
+func describeFiles(files, bytes int64) string {
+	if files == 1 {
+		return "1 file, " + formatBytes(bytes)
+	}
+	return fmt.Sprintf("%d files, %s", files, formatBytes(bytes))
+}
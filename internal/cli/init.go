@@ -3,6 +3,8 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/fs0414/git-worktree-sync/internal/config"
 	"github.com/spf13/cobra"
@@ -11,22 +13,39 @@ import (
 // InitCmd creates the 'init' command
 func InitCmd() *cobra.Command {
 	var (
-		template string
-		force    bool
+		template      string
+		force         bool
+		listTemplates bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize .gwt.yml configuration file",
 		Long: `Create a .gwt.yml configuration file in the current directory.
-The file will be created based on auto-detected project type or specified template.`,
+The file will be created based on auto-detected project type or specified template.
+When several project types are detected, or several templates are given
+(e.g. -t rails,node), their resources are merged into one config.
+
+Workspaces declared in pnpm-workspace.yaml, package.json, go.work or
+Cargo.toml add entries for the dependency and build directories of every
+member package.
+
+Besides the built-in templates, user templates are loaded from
+~/.config/gws/templates/*.yml and the repository's .gws/templates/*.yml.
+A user template is a config file that may also set name, description and
+detect rules; it replaces a built-in template of the same name.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if listTemplates {
+				return runListTemplates()
+			}
 			return runInit(template, force)
 		},
 	}
 
-	cmd.Flags().StringVarP(&template, "template", "t", "", "Template to use (node, rails, go, rust, default)")
+	cmd.Flags().StringVarP(&template, "template", "t", "", "Templates to use, comma-separated (see --list-templates)")
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing .gwt.yml")
+	cmd.Flags().BoolVar(&listTemplates, "list-templates", false, "List available templates and exit")
+	cmd.MarkFlagsMutuallyExclusive("template", "list-templates")
 
 	return cmd
 }
@@ -42,25 +61,44 @@ func runInit(templateName string, force bool) error {
 		return fmt.Errorf(".gwt.yml already exists. Use --force to overwrite")
 	}
 
-	var cfg *config.Config
-	var projectType config.ProjectType
+	templates, err := config.LoadTemplates(currentDir)
+	if err != nil {
+		return fmt.Errorf("failed to load templates: %w", err)
+	}
 
+	var selected []config.Template
 	if templateName != "" {
-		// Use specified template
-		projectType = config.ProjectType(templateName)
-		cfg = config.GetTemplate(projectType)
-		fmt.Printf("Using template: %s\n", templateName)
+		// Use specified templates
+		for _, name := range strings.Split(templateName, ",") {
+			template, err := config.FindTemplate(templates, strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			selected = append(selected, template)
+		}
+		fmt.Printf("Using template: %s\n", joinTemplateNames(selected))
 	} else {
-		// Auto-detect project type
-		projectType = config.DetectProjectType(currentDir)
-		cfg = config.GetTemplate(projectType)
+		// Auto-detect project types
+		selected = config.DetectTemplates(templates, currentDir)
 
-		if projectType != config.ProjectTypeDefault {
-			fmt.Printf("🔍 Detected project type: %s\n", projectType)
+		if len(selected) > 0 {
+			fmt.Printf("🔍 Detected project type: %s\n", joinTemplateNames(selected))
 		} else {
 			fmt.Println("🔍 Using default template")
+			selected = []config.Template{{Config: config.GetDefaultConfig()}}
 		}
 	}
+	cfg := config.MergeTemplates(selected)
+
+	// Add the dependency and build directories of workspace members
+	workspaces, err := config.DetectWorkspaces(currentDir)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to read workspaces: %v\n", err)
+	}
+	for _, ws := range workspaces {
+		fmt.Printf("🔍 Detected %s workspace with %d members\n", ws.Tool, len(ws.Members))
+		cfg.AddResources(config.WorkspaceResources(currentDir, ws))
+	}
 
 	// Save config
 	if err := cfg.Save(currentDir); err != nil {
@@ -72,3 +110,36 @@ func runInit(templateName string, force bool) error {
 
 	return nil
 }
+
+// joinTemplateNames formats template names for display
+func joinTemplateNames(templates []config.Template) string {
+	names := make([]string, len(templates))
+	for i, template := range templates {
+		names[i] = template.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func runListTemplates() error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	templates, err := config.LoadTemplates(currentDir)
+	if err != nil {
+		return fmt.Errorf("failed to load templates: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION\tDETECTS\tSOURCE")
+	for _, template := range templates {
+		markers := append(append([]string{}, template.Detect.All...), template.Detect.Any...)
+		detects := strings.Join(markers, ", ")
+		if detects == "" {
+			detects = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", template.Name, template.Description, detects, displayOrigin(template.Source, currentDir))
+	}
+	return w.Flush()
+}
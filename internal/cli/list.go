@@ -1,9 +1,13 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"text/template"
 
 	"github.com/fs0414/git-worktree-sync/internal/config"
 	"github.com/fs0414/git-worktree-sync/internal/git"
@@ -13,23 +17,89 @@ import (
 
 // ListCmd creates the 'list' command
 func ListCmd() *cobra.Command {
-	var verbose bool
+	var (
+		verbose    bool
+		jsonOutput bool
+		format     string
+		verify     bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all worktrees and their sync status",
-		Long:  `Display all git worktrees with their paths and synchronization status.`,
+		Long: `Display all git worktrees with their paths and synchronization status.
+
+Use --json for machine-readable output, or --format to render each worktree
+with a Go template, e.g. --format '{{.Branch}} {{.Path}} {{.Sync.Synced}}'.
+
+Copied resources are checked by file sizes and modification times. Use
+--verify to also compare their contents with the main worktree.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runList(verbose)
+			return runList(verbose, jsonOutput, format, verify)
 		},
 	}
 
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed information")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	cmd.Flags().StringVar(&format, "format", "", "Format each worktree using a Go template")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Compare the contents of copied resources with the main worktree")
+	cmd.MarkFlagsMutuallyExclusive("json", "format")
 
 	return cmd
 }
 
-func runList(verbose bool) error {
+// listSchemaVersion is bumped whenever the JSON output changes incompatibly
+const listSchemaVersion = 1
+
+// listOutput is the JSON document emitted by 'gws list --json'
+type listOutput struct {
+	SchemaVersion int            `json:"schema_version"`
+	Repository    string         `json:"repository"`
+	Worktrees     []worktreeInfo `json:"worktrees"`
+}
+
+// worktreeInfo describes a worktree for JSON and template output
+type worktreeInfo struct {
+	Branch         string      `json:"branch"`
+	Path           string      `json:"path"`
+	Head           string      `json:"head"`
+	IsMain         bool        `json:"is_main"`
+	Detached       bool        `json:"detached"`
+	Locked         bool        `json:"locked"`
+	LockedReason   string      `json:"locked_reason,omitempty"`
+	Prunable       bool        `json:"prunable"`
+	PrunableReason string      `json:"prunable_reason,omitempty"`
+	Sync           *syncStatus `json:"sync"`
+}
+
+// syncStatus is the sync state of a linked worktree. It is nil for the main worktree.
+type syncStatus struct {
+	Synced    bool             `json:"synced"`
+	Error     string           `json:"error,omitempty"`
+	Resources []resourceStatus `json:"resources"`
+}
+
+type resourceStatus struct {
+	Resource     string `json:"resource"`
+	State        string `json:"state"`
+	Detail       string `json:"detail,omitempty"`
+	ExpectedMode string `json:"expected_mode"`
+	Kind         string `json:"kind"`
+	LinkTarget   string `json:"link_target,omitempty"`
+	PointsToMain bool   `json:"points_to_main"`
+}
+
+func runList(verbose, jsonOutput bool, format string, verify bool) error {
+	// Parse the template up front so that errors are reported before any work
+	var tmpl *template.Template
+	if format != "" {
+		var err error
+		tmpl, err = template.New("format").Parse(format)
+		if err != nil {
+			return fmt.Errorf("invalid format template: %w", err)
+		}
+	}
+
 	// Check if we're in a git repository
 	currentDir, err := os.Getwd()
 	if err != nil {
@@ -41,7 +111,7 @@ func runList(verbose bool) error {
 	}
 
 	// Get repository name
-	repoPath, err := git.GetMainWorktreePath(currentDir)
+	repoPath, err := git.GetWorktreeMainPath(currentDir)
 	if err != nil {
 		return fmt.Errorf("failed to get repository path: %w", err)
 	}
@@ -53,61 +123,131 @@ func runList(verbose bool) error {
 		return fmt.Errorf("failed to list worktrees: %w", err)
 	}
 
-	if len(worktrees) == 0 {
-		fmt.Println("No worktrees found")
+	// Load config
+	cfg := config.GetDefaultConfig()
+	if layered, err := config.LoadLayered(repoPath); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config: %v\n", err)
+	} else {
+		cfg = layered.Config
+	}
+
+	infos := collectWorktreeInfo(cfg, worktrees, verify)
+
+	switch {
+	case jsonOutput:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listOutput{
+			SchemaVersion: listSchemaVersion,
+			Repository:    repoName,
+			Worktrees:     infos,
+		})
+	case tmpl != nil:
+		for _, info := range infos {
+			if err := tmpl.Execute(os.Stdout, info); err != nil {
+				return fmt.Errorf("failed to execute format template: %w", err)
+			}
+			fmt.Println()
+		}
+		return nil
+	default:
+		printWorktreeList(repoName, infos, verbose)
 		return nil
 	}
+}
 
-	// Load config
-	var cfg *config.Config
-	if config.Exists(repoPath) {
-		cfg, err = config.Load(repoPath)
+// collectWorktreeInfo gathers the sync status of every worktree
+func collectWorktreeInfo(cfg *config.Config, worktrees []git.Worktree, verify bool) []worktreeInfo {
+	infos := make([]worktreeInfo, 0, len(worktrees))
+
+	var mainPath string
+	for _, wt := range worktrees {
+		info := worktreeInfo{
+			Branch:         wt.Branch,
+			Path:           wt.Path,
+			Head:           wt.Head,
+			IsMain:         wt.IsMain,
+			Detached:       wt.Detached,
+			Locked:         wt.Locked,
+			LockedReason:   wt.LockedReason,
+			Prunable:       wt.Prunable,
+			PrunableReason: wt.PrunableReason,
+		}
+
+		if wt.IsMain {
+			mainPath = wt.Path
+			infos = append(infos, info)
+			continue
+		}
+
+		// Check sync status
+		info.Sync = &syncStatus{Resources: []resourceStatus{}}
+		report, err := sync.CheckSyncStatus(cfg, mainPath, wt.Path, verify)
 		if err != nil {
-			fmt.Printf("⚠️  Failed to load config: %v\n", err)
-			cfg = config.GetDefaultConfig()
+			info.Sync.Error = err.Error()
+			infos = append(infos, info)
+			continue
 		}
-	} else {
-		cfg = config.GetDefaultConfig()
+
+		info.Sync.Synced = report.Synced()
+		for _, rs := range report.Resources {
+			info.Sync.Resources = append(info.Sync.Resources, resourceStatus{
+				Resource:     rs.Resource,
+				State:        rs.State,
+				Detail:       rs.Detail,
+				ExpectedMode: rs.ExpectedMode,
+				Kind:         rs.Kind,
+				LinkTarget:   rs.LinkTarget,
+				PointsToMain: rs.PointsToMain,
+			})
+		}
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// printWorktreeList renders the human-readable worktree list
+func printWorktreeList(repoName string, infos []worktreeInfo, verbose bool) {
+	if len(infos) == 0 {
+		fmt.Println("No worktrees found")
+		return
 	}
 
 	fmt.Printf("📂 Worktrees for repository: %s\n\n", repoName)
 
-	var mainPath string
 	syncedCount := 0
 	notSyncedCount := 0
 
-	for _, wt := range worktrees {
+	for _, info := range infos {
 		// Display worktree info
-		branch := wt.Branch
+		branch := info.Branch
 		if branch == "" {
 			branch = "(detached)"
+			if len(info.Head) >= 7 {
+				branch = fmt.Sprintf("(detached %s)", info.Head[:7])
+			}
 		}
 
 		var status string
-		var syncStatus bool
-
-		if wt.IsMain {
+		switch {
+		case info.IsMain:
 			status = "(main worktree)"
-			mainPath = wt.Path
-		} else {
-			// Check sync status
-			syncStatus, err = sync.CheckSyncStatus(cfg, mainPath, wt.Path)
-			if err != nil {
-				status = "(error checking status)"
-			} else if syncStatus {
-				status = "(synced)"
-				syncedCount++
-			} else {
-				status = "(not synced)"
-				notSyncedCount++
-			}
+		case info.Sync.Error != "":
+			status = "(error checking status)"
+		case info.Sync.Synced:
+			status = "(synced)"
+			syncedCount++
+		default:
+			status = fmt.Sprintf("(not synced: %s)", summarizeStates(info.Sync.Resources))
+			notSyncedCount++
 		}
 
 		// Format output
 		var icon string
-		if wt.IsMain {
+		if info.IsMain {
 			icon = "  "
-		} else if syncStatus {
+		} else if info.Sync.Synced {
 			icon = "✓ "
 		} else {
 			icon = "✗ "
@@ -115,23 +255,25 @@ func runList(verbose bool) error {
 
 		// Adjust spacing for alignment
 		branchDisplay := fmt.Sprintf("%-20s", branch)
-		pathDisplay := fmt.Sprintf("%-40s", wt.Path)
+		pathDisplay := fmt.Sprintf("%-40s", info.Path)
+
+		if info.Locked {
+			status += " [locked]"
+		}
+		if info.Prunable {
+			status += " [prunable]"
+		}
 
 		fmt.Printf("%s%s %s %s\n", icon, branchDisplay, pathDisplay, status)
 
 		// Show verbose info if requested
-		if verbose && !wt.IsMain {
-			fmt.Printf("   Resources: ")
-			if syncStatus {
-				fmt.Printf("All synced\n")
-			} else {
-				fmt.Printf("Missing resources\n")
-			}
+		if verbose && info.Sync != nil && info.Sync.Error == "" {
+			printResourceTable(info.Sync.Resources)
 		}
 	}
 
 	// Summary
-	fmt.Printf("\nTotal: %d worktrees", len(worktrees))
+	fmt.Printf("\nTotal: %d worktrees", len(infos))
 	if syncedCount > 0 || notSyncedCount > 0 {
 		fmt.Printf(" (%d synced, %d not synced)", syncedCount, notSyncedCount)
 	}
@@ -140,6 +282,53 @@ func runList(verbose bool) error {
 	if notSyncedCount > 0 {
 		fmt.Println("\nRun 'gws sync <path>' to sync unsynced worktrees")
 	}
+}
+
+// summarizeStates formats the number of resources in each unsynced state
+func summarizeStates(resources []resourceStatus) string {
+	counts := make(map[string]int)
+	for _, rs := range resources {
+		counts[rs.State]++
+	}
+
+	var parts []string
+	for _, state := range []string{
+		sync.StateMissing,
+		sync.StateBroken,
+		sync.StateModified,
+		sync.StateStale,
+		sync.StateUnmanaged,
+	} {
+		if counts[state] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[state], state))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// printResourceTable renders the per-resource status of a worktree
+func printResourceTable(resources []resourceStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "   RESOURCE\tEXPECTED\tACTUAL\tSTATE\tDETAIL")
+
+	for _, rs := range resources {
+		detail := rs.Detail
+		if rs.Kind == sync.KindSymlink {
+			target := "→ " + rs.LinkTarget
+			if rs.PointsToMain {
+				target += " (main)"
+			}
+			if detail != "" {
+				detail = target + "; " + detail
+			} else {
+				detail = target
+			}
+		}
+
+		fmt.Fprintf(w, "   %s\t%s\t%s\t%s\t%s\n", rs.Resource, rs.ExpectedMode, rs.Kind, rs.State, detail)
+	}
 
-	return nil
+	w.Flush()
+	fmt.Println()
 }
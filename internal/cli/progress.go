@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fs0414/git-worktree-sync/internal/sync"
+)
+
+// lineInterval is how often plain progress lines are printed
+const lineInterval = 5 * time.Second
+
+// barWidth is the width of the progress bar in characters
+const barWidth = 24
+
+// newProgress returns a progress reporter for stdout: a live progress bar
+// on terminals and plain lines otherwise. Nothing is shown until files are
+// being copied.
+func newProgress() sync.Progress {
+	if isTerminal(os.Stdout) {
+		return &barProgress{out: os.Stdout}
+	}
+	return &lineProgress{out: os.Stdout}
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// barProgress redraws a single progress line
+type barProgress struct {
+	out   io.Writer
+	drawn bool
+}
+
+func (p *barProgress) Update(stats sync.ProgressStats) {
+	if stats.Files == 0 {
+		return
+	}
+
+	filled := 0
+	if stats.Bytes > 0 {
+		filled = min(max(int(stats.BytesDone*barWidth/stats.Bytes), 0), barWidth)
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	fmt.Fprintf(p.out, "\r\033[K[%s] %s", bar, describeProgress(stats))
+	p.drawn = true
+}
+
+func (p *barProgress) Done(stats sync.ProgressStats) {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+	}
+}
+
+// lineProgress prints a progress line every lineInterval
+type lineProgress struct {
+	out     io.Writer
+	printed time.Duration
+}
+
+func (p *lineProgress) Update(stats sync.ProgressStats) {
+	if stats.Files == 0 || stats.Elapsed-p.printed < lineInterval {
+		return
+	}
+	fmt.Fprintf(p.out, "  %s\n", describeProgress(stats))
+	p.printed = stats.Elapsed
+}
+
+func (p *lineProgress) Done(stats sync.ProgressStats) {}
+
+// describeProgress formats the counts and ETA of a sync in progress
+func describeProgress(stats sync.ProgressStats) string {
+	text := fmt.Sprintf("%d/%d resources, %d/%d files, %s/%s",
+		stats.ResourcesDone, stats.Resources,
+		stats.FilesDone, stats.Files,
+		formatBytes(stats.BytesDone), formatBytes(stats.Bytes))
+	if eta := stats.ETA().Round(time.Second); eta > 0 {
+		text += ", ETA " + eta.String()
+	}
+	return text
+}
+
+// formatBytes formats a size with a binary unit, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
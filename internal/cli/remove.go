@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+	"github.com/fs0414/git-worktree-sync/internal/git"
+	"github.com/fs0414/git-worktree-sync/internal/hooks"
+	"github.com/fs0414/git-worktree-sync/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// RemoveCmd creates the 'remove' command
+func RemoveCmd() *cobra.Command {
+	var (
+		force             bool
+		deleteBranch      bool
+		forceDeleteBranch bool
+		dry               dryRunFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "remove <branch|path>",
+		Short: "Remove a worktree and its synced resources",
+		Long: `Remove a git worktree created by gws.
+Symlinked resources are unlinked first so that their targets in the main
+worktree are never touched. The worktree must not have uncommitted changes
+unless --force is given.
+
+--delete-branch deletes the branch only if it is merged; use
+--force-delete-branch to delete it regardless.
+
+With --dry-run, the links to unlink and the git commands that would run are
+shown without changing anything.`,
+		Aliases: []string{"rm"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := dry.validate(); err != nil {
+				return err
+			}
+			return runRemove(args[0], force, deleteBranch || forceDeleteBranch, forceDeleteBranch, dry)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Remove even if the worktree has uncommitted changes")
+	cmd.Flags().BoolVarP(&deleteBranch, "delete-branch", "d", false, "Delete the worktree's branch after removal if it is merged")
+	cmd.Flags().BoolVar(&forceDeleteBranch, "force-delete-branch", false, "Delete the worktree's branch after removal even if it is not merged")
+	dry.addFlags(cmd)
+
+	return cmd
+}
+
+func runRemove(target string, force, deleteBranch, forceDeleteBranch bool, dry dryRunFlags) error {
+	// Check if we're in a git repository
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	if !git.IsGitRepository(currentDir) {
+		return fmt.Errorf("not a git repository")
+	}
+
+	mainPath, err := git.GetWorktreeMainPath(currentDir)
+	if err != nil {
+		return err
+	}
+
+	wt, err := git.FindWorktree(target)
+	if err != nil {
+		return err
+	}
+	if wt.IsMain {
+		return fmt.Errorf("refusing to remove the main worktree")
+	}
+	// git refuses to remove a locked worktree even with --force
+	if wt.Locked {
+		reason := ""
+		if wt.LockedReason != "" {
+			reason = " (" + wt.LockedReason + ")"
+		}
+		return fmt.Errorf("worktree is locked%s; run git worktree unlock %s first", reason, wt.Path)
+	}
+
+	// Load config from main worktree
+	layered, err := config.LoadLayered(mainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := layered.Config
+
+	hookEnv := hooks.Env{
+		Branch:       wt.Branch,
+		WorktreePath: wt.Path,
+		MainPath:     mainPath,
+	}
+
+	if !force {
+		if err := checkClean(cfg, mainPath, wt.Path); err != nil {
+			return err
+		}
+	}
+
+	// The hook runs only once the worktree is known to be removable
+	var d *dryRun
+	if dry.enabled {
+		d = newDryRun("remove", mainPath, wt.Path)
+		d.hook(cfg, hooks.PreRemove)
+	} else {
+		if err := hooks.Run(cfg, hooks.PreRemove, hookEnv); err != nil {
+			return err
+		}
+		// The hook may have left files behind that git would refuse to remove
+		if !force {
+			if err := checkClean(cfg, mainPath, wt.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if d != nil {
+		d.unlink(sync.ManagedSymlinks(cfg, mainPath, wt.Path))
+		d.git(git.RemoveWorktreeArgs(wt.Path, force))
+		d.git(git.PruneWorktreesArgs())
+		if deleteBranch && wt.Branch != "" {
+			d.git(git.DeleteBranchArgs(wt.Branch, forceDeleteBranch))
+		}
+		return d.print(dry.json)
+	}
+
+	fmt.Printf("Removing worktree at %s...\n", wt.Path)
+
+	// Unlink symlinks before git removes the directory so that it never
+	// follows them into the main worktree
+	unlinked, restore, err := sync.UnlinkResources(cfg, mainPath, wt.Path)
+	for _, resource := range unlinked {
+		fmt.Printf("✓ Unlinked %s\n", resource)
+	}
+	if err == nil {
+		err = git.RemoveWorktree(wt.Path, force)
+	}
+	if err != nil {
+		// The worktree stays, so it gets its links back
+		if restoreErr := restore(); restoreErr != nil {
+			fmt.Printf("✗ %v\n", restoreErr)
+		} else {
+			for _, resource := range unlinked {
+				fmt.Printf("↩ Restored link %s\n", resource)
+			}
+		}
+		return err
+	}
+	if err := git.PruneWorktrees(); err != nil {
+		return err
+	}
+	fmt.Println("✓ Removed worktree")
+
+	if deleteBranch {
+		if wt.Branch == "" {
+			fmt.Println("⚠️  Worktree has no branch to delete (detached HEAD)")
+		} else {
+			if err := git.DeleteBranch(wt.Branch, forceDeleteBranch); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Deleted branch %s\n", wt.Branch)
+		}
+	}
+
+	fmt.Println("\n✨ Done!")
+	return nil
+}
+
+// checkClean returns an error if the worktree at path has uncommitted
+// changes, ignoring the symlinks gws created
+func checkClean(cfg *config.Config, mainPath, path string) error {
+	changes, err := git.UncommittedChanges(path)
+	if err != nil {
+		return err
+	}
+
+	managed := make(map[string]bool)
+	for _, resource := range sync.ManagedSymlinks(cfg, mainPath, path) {
+		managed[resource] = true
+	}
+
+	var dirty []string
+	for _, change := range changes {
+		if !managed[change] {
+			dirty = append(dirty, change)
+		}
+	}
+
+	if len(dirty) > 0 {
+		return fmt.Errorf("worktree has uncommitted changes:\n  %s\nUse --force to remove anyway", strings.Join(dirty, "\n  "))
+	}
+	return nil
+}
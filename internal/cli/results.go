@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/glob"
+	"github.com/fs0414/git-worktree-sync/internal/hooks"
+	"github.com/fs0414/git-worktree-sync/internal/sync"
+)
+
+// printSyncResults displays sync results and returns the number of resources
+// that were linked, copied or replaced
+func printSyncResults(results []sync.SyncResult) int {
+	syncCount := 0
+	for _, result := range results {
+		if result.Mode == "skip" {
+			if glob.HasMeta(result.Resource) {
+				fmt.Printf("⚠️  Skipped %s (no matches in source)\n", result.Resource)
+			} else {
+				fmt.Printf("⚠️  Skipped %s (not found in source)\n", result.Resource)
+			}
+			continue
+		}
+		if result.Mode == "cancelled" {
+			fmt.Printf("⚠️  Cancelled %s (an earlier resource failed)\n", result.Resource)
+			continue
+		}
+		if result.Mode == "held" {
+			fmt.Printf("⚠️  Not syncing %s: %s\n", result.Resource, result.Reason)
+			continue
+		}
+		if result.Mode == "exists" {
+			if result.Warning != "" {
+				fmt.Printf("⚠️  %s: %s\n", result.Resource, result.Warning)
+			}
+			continue // Skip already existing resources
+		}
+		if !result.Success {
+			fmt.Printf("✗ Failed to sync %s: %v\n", result.Resource, result.Error)
+			continue
+		}
+
+		switch result.Mode {
+		case "symlink":
+			fmt.Printf("✓ Linked %s\n", result.Resource)
+		case "copy":
+			var details []string
+			if result.Strategy != "" {
+				details = append(details, result.Strategy)
+			}
+			if result.Excluded > 0 {
+				details = append(details, fmt.Sprintf("%d excluded", result.Excluded))
+			}
+			if len(details) > 0 {
+				fmt.Printf("✓ Copied %s (%s)\n", result.Resource, strings.Join(details, ", "))
+			} else {
+				fmt.Printf("✓ Copied %s\n", result.Resource)
+			}
+		case "replaced":
+			fmt.Printf("✓ Replaced %s (backup: %s)\n", result.Resource, result.Backup)
+		}
+		if result.Warning != "" {
+			fmt.Printf("⚠️  %s: %s\n", result.Resource, result.Warning)
+		}
+		syncCount++
+	}
+
+	return syncCount
+}
+
+// printRollback displays each step of a rollback
+func printRollback(steps []sync.RollbackStep) {
+	for _, step := range steps {
+		if step.Error != nil {
+			fmt.Printf("✗ %s: %v\n", step.Description, step.Error)
+		} else {
+			fmt.Printf("↩ %s\n", step.Description)
+		}
+	}
+}
+
+// runInstalls runs the install commands requested by sync results
+func runInstalls(results []sync.SyncResult, env hooks.Env) error {
+	for _, result := range results {
+		if result.Install == "" {
+			continue
+		}
+		if err := hooks.RunInstall(result.Resource, result.Install, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
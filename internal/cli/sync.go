@@ -1,11 +1,13 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/fs0414/git-worktree-sync/internal/config"
 	"github.com/fs0414/git-worktree-sync/internal/git"
+	"github.com/fs0414/git-worktree-sync/internal/hooks"
 	"github.com/fs0414/git-worktree-sync/internal/sync"
 	"github.com/spf13/cobra"
 )
@@ -15,15 +17,29 @@ func SyncCmd() *cobra.Command {
 	var (
 		copyMode bool
 		force    bool
+		jobs     int
+		atomic   bool
+		dry      dryRunFlags
 	)
 
 	cmd := &cobra.Command{
 		Use:   "sync [worktree-path]",
 		Short: "Synchronize resources to an existing worktree",
 		Long: `Synchronize resources from the main worktree to an existing worktree.
-If no path is specified, the current directory is used.`,
+If no path is specified, the current directory is used.
+
+With --dry-run, the action planned for every resource (link, copy, replace,
+exists, skip, hold) and the hooks and install commands that would run are
+shown without changing anything.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if jobs < 0 {
+				return fmt.Errorf("--jobs must not be negative")
+			}
+			if err := dry.validate(); err != nil {
+				return err
+			}
+
 			var targetPath string
 			if len(args) > 0 {
 				targetPath = args[0]
@@ -34,17 +50,20 @@ If no path is specified, the current directory is used.`,
 					return fmt.Errorf("failed to get current directory: %w", err)
 				}
 			}
-			return runSync(targetPath, copyMode, force)
+			return runSync(targetPath, copyMode, force, atomic, jobs, dry)
 		},
 	}
 
 	cmd.Flags().BoolVarP(&copyMode, "copy", "c", false, "Use copy mode instead of symlink")
-	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing resources")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Replace existing resources, backing them up first")
+	cmd.Flags().BoolVar(&atomic, "atomic", false, "Roll back every change if a resource fails to sync")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "Number of resources and files to sync in parallel (default: number of CPUs)")
+	dry.addFlags(cmd)
 
 	return cmd
 }
 
-func runSync(targetPath string, copyMode, force bool) error {
+func runSync(targetPath string, copyMode, force, atomic bool, jobs int, dry dryRunFlags) error {
 	// Check if target is a git repository
 	if !git.IsGitRepository(targetPath) {
 		return fmt.Errorf("not a git repository: %s", targetPath)
@@ -56,51 +75,80 @@ func runSync(targetPath string, copyMode, force bool) error {
 		return fmt.Errorf("failed to get main worktree path: %w", err)
 	}
 
+	if sync.SameDir(mainPath, targetPath) {
+		return fmt.Errorf("%s is the main worktree; run gws sync in a linked worktree", targetPath)
+	}
+
 	// Load config from main worktree
-	var cfg *config.Config
-	if config.Exists(mainPath) {
-		cfg, err = config.Load(mainPath)
+	layered, err := config.LoadLayered(mainPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !layered.HasProjectConfig() {
+		dry.notice("⚠️  No .gwt.yml found in main worktree, using default configuration\n")
+	}
+	cfg := layered.Config
+
+	branchName, err := git.GetCurrentBranch(targetPath)
+	if err != nil {
+		return err
+	}
+
+	hookEnv := hooks.Env{
+		Branch:       branchName,
+		WorktreePath: targetPath,
+		MainPath:     mainPath,
+	}
+
+	if dry.enabled {
+		d := newDryRun("sync", mainPath, targetPath)
+		d.hook(cfg, hooks.PreSync)
+		plans, err := sync.PlanSync(cfg, mainPath, targetPath, sync.Options{
+			Copy:  copyMode,
+			Force: force,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+			return fmt.Errorf("failed to plan sync: %w", err)
 		}
-	} else {
-		fmt.Println("⚠️  No .gwt.yml found in main worktree, using default configuration")
-		cfg = config.GetDefaultConfig()
+		d.resources(plans)
+		d.hook(cfg, hooks.PostSync)
+		return d.print(dry.json)
+	}
+
+	if err := hooks.Run(cfg, hooks.PreSync, hookEnv); err != nil {
+		return err
 	}
 
 	fmt.Printf("🔄 Syncing from main worktree: %s\n", mainPath)
 
 	// Sync resources
-	results, err := sync.SyncResources(cfg, mainPath, targetPath, copyMode)
+	results, err := sync.SyncResources(cfg, mainPath, targetPath, sync.Options{
+		Copy:     copyMode,
+		Force:    force,
+		Jobs:     jobs,
+		Progress: newProgress(),
+		Atomic:   atomic,
+	})
+	var rollbackErr *sync.RollbackError
+	if errors.As(err, &rollbackErr) {
+		printSyncResults(results)
+		fmt.Println("\nRolling back changes...")
+		printRollback(rollbackErr.Steps)
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("failed to sync resources: %w", err)
 	}
 
 	// Display sync results
-	syncCount := 0
-	for _, result := range results {
-		if result.Mode == "skip" {
-			fmt.Printf("⚠️  Skipped %s (not found in source)\n", result.Resource)
-			continue
-		}
-		if result.Mode == "exists" {
-			if force {
-				// TODO: Implement force overwrite
-				fmt.Printf("⚠️  %s already exists (force overwrite not yet implemented)\n", result.Resource)
-			}
-			continue
-		}
-		if !result.Success {
-			fmt.Printf("✗ Failed to sync %s: %v\n", result.Resource, result.Error)
-			continue
-		}
+	syncCount := printSyncResults(results)
 
-		if result.Mode == "symlink" {
-			fmt.Printf("✓ Linked %s\n", result.Resource)
-		} else if result.Mode == "copy" {
-			fmt.Printf("✓ Copied %s\n", result.Resource)
-		}
-		syncCount++
+	if err := runInstalls(results, hookEnv); err != nil {
+		return err
+	}
+
+	if err := hooks.Run(cfg, hooks.PostSync, hookEnv); err != nil {
+		return err
 	}
 
 	if syncCount > 0 {
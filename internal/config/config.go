@@ -3,24 +3,115 @@ package config
 import (
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 const (
 	ConfigFileName       = ".gwt.yml"
+	LocalConfigFileName  = ".gwt.local.yml"
 	GlobalConfigDir      = ".config/gws"
 	GlobalConfigFileName = "config.yml"
 )
 
+// Values of the package_manager setting
+const (
+	PackageManagerAuto    = "auto"
+	PackageManagerNone    = "none"
+	PackageManagerNpm     = "npm"
+	PackageManagerPnpm    = "pnpm"
+	PackageManagerYarn    = "yarn"
+	PackageManagerYarnPnP = "yarn-pnp"
+	PackageManagerBun     = "bun"
+)
+
+// PackageManagers lists the valid values of the package_manager setting
+var PackageManagers = []string{
+	PackageManagerAuto,
+	PackageManagerNone,
+	PackageManagerNpm,
+	PackageManagerPnpm,
+	PackageManagerYarn,
+	PackageManagerYarnPnP,
+	PackageManagerBun,
+}
+
 // Config represents the .gwt.yml configuration file
 type Config struct {
-	Resources     Resources         `yaml:"resources"`
-	WorktreePath  string            `yaml:"worktree_path"`
-	Exclude       []string          `yaml:"exclude"`
-	Hooks         map[string]string `yaml:"hooks,omitempty"`
+	Resources    Resources         `yaml:"resources"`
+	WorktreePath string            `yaml:"worktree_path"`
+	Exclude      []string          `yaml:"exclude"`
+	Hooks        map[string]string `yaml:"hooks,omitempty"`
+	// Merge controls how lists in this file combine with lower config
+	// layers: "append" (default) or "replace"
+	Merge string `yaml:"merge,omitempty"`
+	// PackageManager selects how Node dependencies are synced: "auto"
+	// (default, detected from the lockfile), "none" or a package manager name
+	PackageManager string `yaml:"package_manager,omitempty"`
+	// ResourceOptions holds per-resource settings keyed by resource entry
+	ResourceOptions map[string]ResourceOptions `yaml:"resource_options,omitempty"`
+}
+
+// Actions taken when the lockfiles of a resource differ from the main worktree
+const (
+	// LockfileWarn syncs the resource as usual and reports a warning
+	LockfileWarn = "warn"
+	// LockfileCopy copies the resource instead of linking it and runs its install command
+	LockfileCopy = "copy"
+	// LockfileHook leaves the resource out and runs its install command
+	LockfileHook = "hook"
+)
+
+// ResourceOptions are settings for a single resource
+type ResourceOptions struct {
+	// Lockfiles are the files, relative to the worktree root, whose content
+	// determines the content of the resource
+	Lockfiles []string `yaml:"lockfiles,omitempty"`
+	// OnLockfileChange is the action taken when a lockfile differs from the
+	// main worktree: "warn" (default), "copy" or "hook"
+	OnLockfileChange string `yaml:"on_lockfile_change,omitempty"`
+	// Install is the command that rebuilds the resource in the worktree
+	Install string `yaml:"install,omitempty"`
+	// CopyStrategy selects how files are copied when the resource is synced
+	// in copy mode: "auto" (default), "reflink", "hardlink" or "copy"
+	CopyStrategy string `yaml:"copy_strategy,omitempty"`
+}
+
+// Strategies for copying the files of a resource
+const (
+	// CopyStrategyAuto clones files when the filesystem supports it and
+	// copies them otherwise
+	CopyStrategyAuto = "auto"
+	// CopyStrategyReflink clones files, sharing their blocks until modified
+	CopyStrategyReflink = "reflink"
+	// CopyStrategyHardlink links files, so changes show up in both worktrees
+	CopyStrategyHardlink = "hardlink"
+	// CopyStrategyCopy copies the content of files
+	CopyStrategyCopy = "copy"
+)
+
+// CopyStrategies lists the valid values of the copy_strategy setting
+var CopyStrategies = []string{
+	CopyStrategyAuto,
+	CopyStrategyReflink,
+	CopyStrategyHardlink,
+	CopyStrategyCopy,
+}
+
+// OptionsFor returns the options of a resource. Options keyed by the
+// resource path take precedence over options keyed by the config entry
+// (e.g. a pattern) that the resource was expanded from.
+func (c *Config) OptionsFor(entry, resource string) (ResourceOptions, bool) {
+	if opts, ok := c.ResourceOptions[resource]; ok {
+		return opts, true
+	}
+	opts, ok := c.ResourceOptions[entry]
+	return opts, ok
 }
 
 // Resources defines which resources to sync
@@ -29,6 +120,20 @@ type Resources struct {
 	Copy    []string `yaml:"copy"`
 }
 
+// AddResources appends resources that are not already configured in either list
+func (c *Config) AddResources(resources Resources) {
+	for _, resource := range resources.Symlink {
+		if !contains(c.Resources.Symlink, resource) && !contains(c.Resources.Copy, resource) {
+			c.Resources.Symlink = append(c.Resources.Symlink, resource)
+		}
+	}
+	for _, resource := range resources.Copy {
+		if !contains(c.Resources.Symlink, resource) && !contains(c.Resources.Copy, resource) {
+			c.Resources.Copy = append(c.Resources.Copy, resource)
+		}
+	}
+}
+
 // Load loads the configuration from .gwt.yml in the given directory
 func Load(dir string) (*Config, error) {
 	configPath := filepath.Join(dir, ConfigFileName)
@@ -37,19 +142,33 @@ func Load(dir string) (*Config, error) {
 
 // LoadFromPath loads configuration from a specific path
 func LoadFromPath(path string) (*Config, error) {
+	cfg, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set default worktree path if not specified
+	if cfg.WorktreePath == "" {
+		cfg.WorktreePath = "../{branch}"
+	}
+
+	return cfg, nil
+}
+
+// parseFile reads and validates a config file without applying defaults
+func parseFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if err := Validate(path, data); err != nil {
+		return nil, err
 	}
 
-	// Set default worktree path if not specified
-	if cfg.WorktreePath == "" {
-		cfg.WorktreePath = "../{branch}"
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
 
 	return &cfg, nil
@@ -82,19 +201,125 @@ func Exists(dir string) bool {
 	return err == nil
 }
 
-// ResolveWorktreePath resolves the worktree path with branch name substitution
-func (c *Config) ResolveWorktreePath(branchName string) string {
-	return strings.ReplaceAll(c.WorktreePath, "{branch}", branchName)
+// ResolveWorktreePath expands the worktree_path template for a branch and
+// returns an absolute path. Relative paths are resolved against the main
+// worktree, and the result must lie outside of it.
+//
+// Supported variables:
+//
+//	{repo}         name of the main worktree directory
+//	{branch}       branch name, slashes create nested directories
+//	{branch_slug}  branch name with slashes and unsafe characters replaced by "-"
+//	{user}         current user name
+//	{date}         current date (YYYY-MM-DD)
+//	{main}         absolute path of the main worktree
+//
+// A leading "~" is expanded to the home directory.
+func (c *Config) ResolveWorktreePath(mainPath, branchName string) (string, error) {
+	vars := map[string]string{
+		"repo":        filepath.Base(mainPath),
+		"branch":      branchName,
+		"branch_slug": slugify(branchName),
+		"user":        currentUser(),
+		"date":        time.Now().Format("2006-01-02"),
+		"main":        mainPath,
+	}
+
+	var unknown error
+	expanded := templateVarPattern.ReplaceAllStringFunc(c.WorktreePath, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := vars[name]
+		if !ok && unknown == nil {
+			unknown = fmt.Errorf("unknown variable %s in worktree_path", match)
+		}
+		return value
+	})
+	if unknown != nil {
+		return "", unknown
+	}
+
+	worktreePath, err := expandHome(expanded)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(worktreePath) {
+		worktreePath = filepath.Join(mainPath, worktreePath)
+	}
+	worktreePath = filepath.Clean(worktreePath)
+
+	if err := ValidateWorktreePath(mainPath, worktreePath); err != nil {
+		return "", err
+	}
+
+	return worktreePath, nil
+}
+
+// ValidateWorktreePath checks that a worktree path lies outside the main worktree
+func ValidateWorktreePath(mainPath, worktreePath string) error {
+	rel, err := filepath.Rel(filepath.Clean(mainPath), filepath.Clean(worktreePath))
+	if err != nil {
+		return nil
+	}
+	if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+		return fmt.Errorf("worktree path %s is inside the main worktree %s", worktreePath, mainPath)
+	}
+	return nil
+}
+
+var (
+	templateVarPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+	unsafeSlugChars    = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// slugify turns a branch name into a single path segment
+func slugify(name string) string {
+	return strings.Trim(unsafeSlugChars.ReplaceAllString(name, "-"), "-")
+}
+
+// currentUser returns the current user name, or an empty string if unknown
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows user names include the domain
+		_, name, found := strings.Cut(u.Username, `\`)
+		if found {
+			return name
+		}
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// expandHome expands a leading ~ to the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, path[1:]), nil
+}
+
+// GlobalConfigPath returns the path of the global configuration file
+func GlobalConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, GlobalConfigDir, GlobalConfigFileName), nil
 }
 
 // LoadGlobalConfig loads the global configuration from ~/.config/gws/config.yml
 func LoadGlobalConfig() (*Config, error) {
-	homeDir, err := os.UserHomeDir()
+	configPath, err := GlobalConfigPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
 
-	configPath := filepath.Join(homeDir, GlobalConfigDir, GlobalConfigFileName)
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		// Return default config if global config doesn't exist
 		return GetDefaultConfig(), nil
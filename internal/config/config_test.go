@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -49,15 +50,66 @@ exclude:
 }
 
 func TestResolveWorktreePath(t *testing.T) {
-	cfg := &Config{
-		WorktreePath: "../{branch}",
+	mainPath := filepath.Join(string(filepath.Separator), "src", "my-app")
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("failed to get home directory: %v", err)
+	}
+
+	tests := []struct {
+		template string
+		branch   string
+		expected string
+	}{
+		{"../{branch}", "feature-branch", filepath.Join(filepath.Dir(mainPath), "feature-branch")},
+		{"../worktrees/{branch}", "feature/auth", filepath.Join(filepath.Dir(mainPath), "worktrees", "feature", "auth")},
+		{"../{repo}-{branch_slug}", "feature/auth", filepath.Join(filepath.Dir(mainPath), "my-app-feature-auth")},
+		{"{main}.worktrees/{branch_slug}", "fix/#12 crash", mainPath + ".worktrees/fix-12-crash"},
+		{"~/worktrees/{repo}/{branch}", "main", filepath.Join(homeDir, "worktrees", "my-app", "main")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			cfg := &Config{WorktreePath: tt.template}
+
+			result, err := cfg.ResolveWorktreePath(mainPath, tt.branch)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != filepath.Clean(tt.expected) {
+				t.Errorf("expected %s, got %s", filepath.Clean(tt.expected), result)
+			}
+		})
+	}
+}
+
+func TestResolveWorktreePathErrors(t *testing.T) {
+	mainPath := filepath.Join(string(filepath.Separator), "src", "my-app")
+
+	templates := []string{
+		"{branch}",           // inside the main worktree
+		"worktrees/{branch}", // inside the main worktree
+		"{main}",             // the main worktree itself
+		"../{unknown}",       // unknown variable
 	}
 
-	result := cfg.ResolveWorktreePath("feature-branch")
-	expected := "../feature-branch"
+	for _, template := range templates {
+		cfg := &Config{WorktreePath: template}
+		if _, err := cfg.ResolveWorktreePath(mainPath, "feature"); err == nil {
+			t.Errorf("expected error for %q", template)
+		}
+	}
+}
+
+func TestResolveWorktreePathVariables(t *testing.T) {
+	cfg := &Config{WorktreePath: "/tmp/{user}/{date}"}
 
-	if result != expected {
-		t.Errorf("expected %s, got %s", expected, result)
+	result, err := cfg.ResolveWorktreePath("/src/my-app", "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(result, "{") {
+		t.Errorf("expected all variables to be substituted, got %s", result)
 	}
 }
 
@@ -0,0 +1,344 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of keys that can be edited with a Document
+const (
+	KeyKindScalar = "scalar"
+	KeyKindList   = "list"
+)
+
+// editableKeys lists the config keys that can be edited and their kind.
+// hooks.<name> keys are scalars.
+var editableKeys = map[string]string{
+	"worktree_path":     KeyKindScalar,
+	"merge":             KeyKindScalar,
+	"package_manager":   KeyKindScalar,
+	"resources.symlink": KeyKindList,
+	"resources.copy":    KeyKindList,
+	"exclude":           KeyKindList,
+}
+
+// KeyKind returns the kind of an editable key
+func KeyKind(key string) (string, error) {
+	if kind, ok := editableKeys[key]; ok {
+		return kind, nil
+	}
+	if name, ok := strings.CutPrefix(key, "hooks."); ok && name != "" && !strings.Contains(name, ".") {
+		return KeyKindScalar, nil
+	}
+	return "", fmt.Errorf("unknown config key: %s", key)
+}
+
+// Document is a config file opened for editing. Edits are applied to the
+// YAML node tree so that comments and key order are preserved.
+type Document struct {
+	path string
+	root *yaml.Node
+}
+
+// OpenDocument opens a config file for editing. A missing file is treated as empty.
+func OpenDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseDocument(path, data)
+}
+
+// DocumentFromConfig creates a read-only view of a config for key lookups
+func DocumentFromConfig(cfg *Config) (*Document, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return parseDocument("", data)
+}
+
+func parseDocument(path string, data []byte) (*Document, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// Empty files have no document node
+	if root.Kind == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(root.Content) == 0 {
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config file: top level is not a mapping")
+	}
+
+	return &Document{path: path, root: &root}, nil
+}
+
+// Path returns the file the document was opened from
+func (d *Document) Path() string {
+	return d.path
+}
+
+// Get returns the values stored at key: a single value for scalar keys and
+// one value per item for list keys. ok is false if the key is not set.
+func (d *Document) Get(key string) (values []string, ok bool, err error) {
+	kind, err := KeyKind(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	node := d.lookup(key)
+	if node == nil {
+		return nil, false, nil
+	}
+
+	if kind == KeyKindScalar {
+		if node.Kind != yaml.ScalarNode {
+			return nil, false, fmt.Errorf("%s is not a scalar", key)
+		}
+		return []string{node.Value}, true, nil
+	}
+
+	if node.Kind != yaml.SequenceNode {
+		return nil, false, fmt.Errorf("%s is not a list", key)
+	}
+	for _, item := range node.Content {
+		values = append(values, item.Value)
+	}
+	return values, true, nil
+}
+
+// Set sets a scalar key
+func (d *Document) Set(key, value string) error {
+	kind, err := KeyKind(key)
+	if err != nil {
+		return err
+	}
+	if kind != KeyKindScalar {
+		return fmt.Errorf("%s is a list; use add or remove", key)
+	}
+
+	parent, name, err := d.ensureParent(key)
+	if err != nil {
+		return err
+	}
+
+	if _, node := findKey(parent, name); node != nil {
+		// Replace the value in place to keep comments attached to the key
+		node.Kind = yaml.ScalarNode
+		node.Tag = "!!str"
+		node.Value = value
+		node.Style = 0
+		node.Content = nil
+		return nil
+	}
+
+	parent.Content = append(parent.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	)
+	return nil
+}
+
+// Add appends a value to a list key. It returns false if the value was already present.
+func (d *Document) Add(key, value string) (bool, error) {
+	kind, err := KeyKind(key)
+	if err != nil {
+		return false, err
+	}
+	if kind != KeyKindList {
+		return false, fmt.Errorf("%s is not a list; use set", key)
+	}
+
+	parent, name, err := d.ensureParent(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, list := findKey(parent, name)
+	if list == nil || (list.Kind == yaml.ScalarNode && list.Tag == "!!null") {
+		if list == nil {
+			list = &yaml.Node{}
+			parent.Content = append(parent.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+				list,
+			)
+		}
+		*list = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	if list.Kind != yaml.SequenceNode {
+		return false, fmt.Errorf("%s is not a list", key)
+	}
+
+	for _, item := range list.Content {
+		if item.Value == value {
+			return false, nil
+		}
+	}
+
+	list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+	return true, nil
+}
+
+// Remove removes a value from a list key, or the whole key if value is empty
+func (d *Document) Remove(key, value string) error {
+	kind, err := KeyKind(key)
+	if err != nil {
+		return err
+	}
+
+	parentKey, name := splitKey(key)
+	parent := d.mapping()
+	if parentKey != "" {
+		parent = d.lookup(parentKey)
+	}
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	index, node := findKey(parent, name)
+	if node == nil {
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	if value == "" {
+		parent.Content = append(parent.Content[:index], parent.Content[index+2:]...)
+		return nil
+	}
+
+	if kind != KeyKindList {
+		return fmt.Errorf("%s is not a list; omit the value to unset it", key)
+	}
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s is not a list", key)
+	}
+
+	for i, item := range node.Content {
+		if item.Value == value {
+			node.Content = append(node.Content[:i], node.Content[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s does not contain %q", key, value)
+}
+
+// Validate checks the edited document against the schema, as Validate
+// checks a config file
+func (d *Document) Validate() error {
+	data, err := d.encode()
+	if err != nil {
+		return err
+	}
+	return Validate(d.path, data)
+}
+
+// Save writes the document back to its file
+func (d *Document) Save() error {
+	if d.path == "" {
+		return fmt.Errorf("document has no file")
+	}
+
+	data, err := d.encode()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(d.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// encode returns the document as YAML
+func (d *Document) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(d.root); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *Document) mapping() *yaml.Node {
+	return d.root.Content[0]
+}
+
+// lookup returns the value node at a dotted key, or nil if it is not set
+func (d *Document) lookup(key string) *yaml.Node {
+	node := d.mapping()
+	for _, part := range strings.Split(key, ".") {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		_, node = findKey(node, part)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// ensureParent returns the mapping holding the last segment of key,
+// creating intermediate mappings as needed
+func (d *Document) ensureParent(key string) (*yaml.Node, string, error) {
+	parentKey, name := splitKey(key)
+
+	node := d.mapping()
+	if parentKey == "" {
+		return node, name, nil
+	}
+
+	for _, part := range strings.Split(parentKey, ".") {
+		_, child := findKey(node, part)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part},
+				child,
+			)
+		} else if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+			*child = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		if child.Kind != yaml.MappingNode {
+			return nil, "", fmt.Errorf("%s is not a mapping", part)
+		}
+		node = child
+	}
+
+	return node, name, nil
+}
+
+// splitKey splits a dotted key into its parent and last segment
+func splitKey(key string) (string, string) {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+// findKey returns the index of the key node and the value node for name in a mapping
+func findKey(mapping *yaml.Node, name string) (int, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return i, mapping.Content[i+1]
+		}
+	}
+	return -1, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocumentEdit(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ConfigFileName)
+	writeConfig(t, configPath, `# Project config
+resources:
+  # Linked dependencies
+  symlink:
+    - node_modules
+  copy: [.env]
+worktree_path: "../{branch}" # next to the repo
+`)
+
+	doc, err := OpenDocument(configPath)
+	if err != nil {
+		t.Fatalf("failed to open document: %v", err)
+	}
+
+	if added, err := doc.Add("resources.symlink", ".venv"); err != nil || !added {
+		t.Fatalf("failed to add value: %v", err)
+	}
+	if added, err := doc.Add("resources.symlink", ".venv"); err != nil || added {
+		t.Errorf("expected duplicate add to be a no-op, got added=%v err=%v", added, err)
+	}
+	if _, err := doc.Add("resources.copy", ".env.local"); err != nil {
+		t.Fatalf("failed to add to flow list: %v", err)
+	}
+	if _, err := doc.Add("exclude", "*.log"); err != nil {
+		t.Fatalf("failed to add to new list: %v", err)
+	}
+	if err := doc.Set("worktree_path", "../wt/{branch_slug}"); err != nil {
+		t.Fatalf("failed to set value: %v", err)
+	}
+	if err := doc.Set("hooks.post_create", "npm ci"); err != nil {
+		t.Fatalf("failed to set hook: %v", err)
+	}
+	if err := doc.Remove("resources.symlink", "node_modules"); err != nil {
+		t.Fatalf("failed to remove value: %v", err)
+	}
+	if err := doc.Validate(); err != nil {
+		t.Fatalf("expected edited document to be valid: %v", err)
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatalf("failed to save document: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	for _, comment := range []string{"# Project config", "# Linked dependencies", "# next to the repo"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("expected comment %q to be preserved:\n%s", comment, data)
+		}
+	}
+	if strings.Index(string(data), "resources:") > strings.Index(string(data), "worktree_path:") {
+		t.Errorf("expected key order to be preserved:\n%s", data)
+	}
+
+	cfg, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("failed to load edited config: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Resources.Symlink, []string{".venv"}) {
+		t.Errorf("unexpected symlink resources: %v", cfg.Resources.Symlink)
+	}
+	if !reflect.DeepEqual(cfg.Resources.Copy, []string{".env", ".env.local"}) {
+		t.Errorf("unexpected copy resources: %v", cfg.Resources.Copy)
+	}
+	if !reflect.DeepEqual(cfg.Exclude, []string{"*.log"}) {
+		t.Errorf("unexpected exclude patterns: %v", cfg.Exclude)
+	}
+	if cfg.WorktreePath != "../wt/{branch_slug}" {
+		t.Errorf("unexpected worktree path: %s", cfg.WorktreePath)
+	}
+	if cfg.Hooks["post_create"] != "npm ci" {
+		t.Errorf("unexpected hooks: %v", cfg.Hooks)
+	}
+}
+
+func TestDocumentErrors(t *testing.T) {
+	doc, err := OpenDocument(filepath.Join(t.TempDir(), "missing.yml"))
+	if err != nil {
+		t.Fatalf("expected missing file to open as empty document: %v", err)
+	}
+
+	if err := doc.Set("resources.symlink", "x"); err == nil {
+		t.Error("expected error setting a list key")
+	}
+	if _, err := doc.Add("worktree_path", "x"); err == nil {
+		t.Error("expected error adding to a scalar key")
+	}
+	if err := doc.Set("symlinks", "x"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+	if err := doc.Remove("exclude", "*.log"); err == nil {
+		t.Error("expected error removing from an unset key")
+	}
+
+	if _, ok, err := doc.Get("worktree_path"); err != nil || ok {
+		t.Errorf("expected unset key, got ok=%v err=%v", ok, err)
+	}
+
+	// Edits are applied even if they break the schema; Validate catches them
+	if err := doc.Set("merge", "bogus"); err != nil {
+		t.Fatalf("failed to set value: %v", err)
+	}
+	if err := doc.Set("hooks.post_crate", "make"); err != nil {
+		t.Fatalf("failed to set hook: %v", err)
+	}
+	var problems ValidationErrors
+	if err := doc.Validate(); !errors.As(err, &problems) || len(problems) != 2 {
+		t.Errorf("expected merge and hook problems, got %v", err)
+	}
+}
+
+func TestDocumentFromConfig(t *testing.T) {
+	doc, err := DocumentFromConfig(GetDefaultConfig())
+	if err != nil {
+		t.Fatalf("failed to create document: %v", err)
+	}
+
+	values, ok, err := doc.Get("resources.symlink")
+	if err != nil || !ok {
+		t.Fatalf("failed to get value: %v", err)
+	}
+	if !reflect.DeepEqual(values, GetDefaultConfig().Resources.Symlink) {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
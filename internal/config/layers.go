@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Merge strategies for lists in a config layer
+const (
+	MergeAppend  = "append"
+	MergeReplace = "replace"
+)
+
+// OriginDefault is the origin of values that come from the built-in defaults
+const OriginDefault = "default"
+
+// Layered is the effective configuration built from all config layers
+type Layered struct {
+	Config *Config
+	// Files are the config files that were found, from lowest to highest precedence
+	Files []string
+	// Origins maps each value to the file it came from. Keys are field names
+	// for scalars ("worktree_path"), "field[item]" for list items
+	// ("resources.symlink[node_modules]"), "hooks.<name>" for hooks and
+	// "resource_options.<resource>" for resource options.
+	Origins map[string]string
+}
+
+// HasProjectConfig reports whether a .gwt.yml or .gwt.local.yml was loaded
+func (l *Layered) HasProjectConfig() bool {
+	for _, file := range l.Files {
+		base := filepath.Base(file)
+		if base == ConfigFileName || base == LocalConfigFileName {
+			return true
+		}
+	}
+	return false
+}
+
+// Origin returns where the value for key came from
+func (l *Layered) Origin(key string) string {
+	return l.Origins[key]
+}
+
+// LayerPaths returns the config files for dir from lowest to highest
+// precedence: the global config, .gwt.yml and .gwt.local.yml
+func LayerPaths(dir string) []string {
+	var paths []string
+	if globalPath, err := GlobalConfigPath(); err == nil {
+		paths = append(paths, globalPath)
+	}
+	return append(paths,
+		filepath.Join(dir, ConfigFileName),
+		filepath.Join(dir, LocalConfigFileName),
+	)
+}
+
+// LoadLayered builds the effective configuration for the repository at dir.
+//
+// Layers are applied in order: built-in defaults, the global config
+// (~/.config/gws/config.yml), the project's .gwt.yml and the git-ignored
+// .gwt.local.yml. Scalars from higher layers override lower ones and hooks
+// are merged by name. Lists (resources.symlink, resources.copy, exclude) are
+// appended to those of lower layers unless the layer sets "merge: replace".
+// Built-in default lists are always replaced by the first file that sets them.
+func LoadLayered(dir string) (*Layered, error) {
+	layered := &Layered{
+		Config:  &Config{Hooks: map[string]string{}},
+		Origins: make(map[string]string),
+	}
+
+	m := &merger{layered: layered, fromDefault: make(map[string]bool)}
+	m.apply(GetDefaultConfig(), OriginDefault)
+	for key := range m.lists() {
+		m.fromDefault[key] = true
+	}
+
+	for _, path := range LayerPaths(dir) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		cfg, err := parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		m.apply(cfg, path)
+		layered.Files = append(layered.Files, path)
+	}
+
+	if len(layered.Config.Hooks) == 0 {
+		layered.Config.Hooks = nil
+	}
+
+	return layered, nil
+}
+
+// merger applies config layers on top of each other
+type merger struct {
+	layered *Layered
+	// fromDefault tracks lists that still hold the built-in defaults
+	fromDefault map[string]bool
+}
+
+// lists returns the mergeable lists of the effective config by key
+func (m *merger) lists() map[string]*[]string {
+	cfg := m.layered.Config
+	return map[string]*[]string{
+		"resources.symlink": &cfg.Resources.Symlink,
+		"resources.copy":    &cfg.Resources.Copy,
+		"exclude":           &cfg.Exclude,
+	}
+}
+
+func (m *merger) apply(layer *Config, origin string) {
+	cfg := m.layered.Config
+
+	if layer.WorktreePath != "" {
+		cfg.WorktreePath = layer.WorktreePath
+		m.layered.Origins["worktree_path"] = origin
+	}
+	if layer.PackageManager != "" {
+		cfg.PackageManager = layer.PackageManager
+		m.layered.Origins["package_manager"] = origin
+	}
+
+	layerLists := map[string][]string{
+		"resources.symlink": layer.Resources.Symlink,
+		"resources.copy":    layer.Resources.Copy,
+		"exclude":           layer.Exclude,
+	}
+	for key, target := range m.lists() {
+		items := layerLists[key]
+		if items == nil {
+			continue
+		}
+
+		if layer.Merge == MergeReplace || m.fromDefault[key] {
+			for _, item := range *target {
+				delete(m.layered.Origins, key+"["+item+"]")
+			}
+			*target = []string{}
+			m.fromDefault[key] = false
+		}
+
+		for _, item := range items {
+			if contains(*target, item) {
+				continue
+			}
+			*target = append(*target, item)
+			m.layered.Origins[key+"["+item+"]"] = origin
+		}
+	}
+
+	for name, command := range layer.Hooks {
+		cfg.Hooks[name] = command
+		m.layered.Origins["hooks."+name] = origin
+	}
+
+	// Options of a resource are replaced as a whole
+	for resource, opts := range layer.ResourceOptions {
+		if cfg.ResourceOptions == nil {
+			cfg.ResourceOptions = make(map[string]ResourceOptions)
+		}
+		cfg.ResourceOptions[resource] = opts
+		m.layered.Origins["resource_options."+resource] = origin
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
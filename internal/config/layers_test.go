@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestLoadLayered(t *testing.T) {
+	homeDir := t.TempDir()
+	repoDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("USERPROFILE", homeDir)
+
+	globalPath := filepath.Join(homeDir, GlobalConfigDir, GlobalConfigFileName)
+	writeConfig(t, globalPath, `resources:
+  copy:
+    - .envrc
+exclude:
+  - "*.swp"
+hooks:
+  post_create: direnv allow
+`)
+	writeConfig(t, filepath.Join(repoDir, ConfigFileName), `resources:
+  symlink:
+    - vendor
+  copy:
+    - .env
+worktree_path: "../{repo}-{branch}"
+hooks:
+  pre_sync: make check
+`)
+	writeConfig(t, filepath.Join(repoDir, LocalConfigFileName), `merge: replace
+exclude:
+  - "*.tmp"
+hooks:
+  post_create: echo local
+`)
+
+	layered, err := LoadLayered(repoDir)
+	if err != nil {
+		t.Fatalf("failed to load layered config: %v", err)
+	}
+	cfg := layered.Config
+
+	// Default symlinks are replaced by the project list
+	if !reflect.DeepEqual(cfg.Resources.Symlink, []string{"vendor"}) {
+		t.Errorf("unexpected symlink resources: %v", cfg.Resources.Symlink)
+	}
+	// Project copy resources are appended to the global ones
+	if !reflect.DeepEqual(cfg.Resources.Copy, []string{".envrc", ".env"}) {
+		t.Errorf("unexpected copy resources: %v", cfg.Resources.Copy)
+	}
+	// The local file replaces the exclude list
+	if !reflect.DeepEqual(cfg.Exclude, []string{"*.tmp"}) {
+		t.Errorf("unexpected exclude patterns: %v", cfg.Exclude)
+	}
+	if cfg.WorktreePath != "../{repo}-{branch}" {
+		t.Errorf("unexpected worktree path: %s", cfg.WorktreePath)
+	}
+	expectedHooks := map[string]string{"post_create": "echo local", "pre_sync": "make check"}
+	if !reflect.DeepEqual(cfg.Hooks, expectedHooks) {
+		t.Errorf("unexpected hooks: %v", cfg.Hooks)
+	}
+
+	if len(layered.Files) != 3 || !layered.HasProjectConfig() {
+		t.Errorf("expected 3 config files, got %v", layered.Files)
+	}
+
+	origins := map[string]string{
+		"resources.copy[.envrc]": globalPath,
+		"resources.copy[.env]":   filepath.Join(repoDir, ConfigFileName),
+		"exclude[*.tmp]":         filepath.Join(repoDir, LocalConfigFileName),
+		"hooks.post_create":      filepath.Join(repoDir, LocalConfigFileName),
+		"worktree_path":          filepath.Join(repoDir, ConfigFileName),
+	}
+	for key, expected := range origins {
+		if origin := layered.Origin(key); origin != expected {
+			t.Errorf("%s: expected origin %s, got %s", key, expected, origin)
+		}
+	}
+	if origin := layered.Origin("exclude[*.swp]"); origin != "" {
+		t.Errorf("expected replaced value to have no origin, got %s", origin)
+	}
+}
+
+func TestLoadLayeredDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	layered, err := LoadLayered(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to load layered config: %v", err)
+	}
+
+	defaults := GetDefaultConfig()
+	if !reflect.DeepEqual(layered.Config.Resources, defaults.Resources) {
+		t.Errorf("expected default resources, got %+v", layered.Config.Resources)
+	}
+	if layered.HasProjectConfig() {
+		t.Error("expected no project config")
+	}
+	if origin := layered.Origin("worktree_path"); origin != OriginDefault {
+		t.Errorf("expected default origin, got %s", origin)
+	}
+}
+
+func TestLoadLayeredInvalidMerge(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoDir := t.TempDir()
+	writeConfig(t, filepath.Join(repoDir, ConfigFileName), "merge: prepend\n")
+
+	if _, err := LoadLayered(repoDir); err == nil {
+		t.Error("expected error for invalid merge strategy")
+	}
+}
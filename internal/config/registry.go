@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template directories. User templates are *.yml or *.yaml files in the
+// global templates directory (~/.config/gws/templates) or in the
+// repository's .gws/templates directory.
+const (
+	TemplatesDirName     = "templates"
+	RepoTemplatesDirName = ".gws/templates"
+)
+
+// OriginBuiltin is the source of the built-in templates
+const OriginBuiltin = "builtin"
+
+// Template is a named configuration template
+type Template struct {
+	Name        string
+	Description string
+	// Source is the file the template was loaded from, or OriginBuiltin
+	Source string
+	// Detect identifies projects the template applies to
+	Detect Detection
+	Config *Config
+}
+
+// templateFile is the YAML format of a user template: a config file with
+// a name, a description and detection rules
+type templateFile struct {
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description"`
+	Detect      Detection `yaml:"detect"`
+	Config      `yaml:",inline"`
+}
+
+var builtinDescriptions = map[ProjectType]string{
+	ProjectTypeNode:    "Node.js",
+	ProjectTypeRails:   "Ruby on Rails",
+	ProjectTypeGo:      "Go",
+	ProjectTypeRust:    "Rust",
+	ProjectTypePython:  "Python",
+	ProjectTypeGradle:  "Java/Kotlin with Gradle",
+	ProjectTypeMaven:   "Java with Maven",
+	ProjectTypeDotnet:  ".NET",
+	ProjectTypePHP:     "PHP with Composer",
+	ProjectTypeElixir:  "Elixir with Mix",
+	ProjectTypeFlutter: "Flutter/Dart",
+	ProjectTypeDefault: "Generic project",
+}
+
+// BuiltinTemplates returns the built-in templates in detection order,
+// followed by the default template
+func BuiltinTemplates() []Template {
+	var templates []Template
+	for _, builtin := range builtinDetections {
+		templates = append(templates, Template{
+			Name:        string(builtin.projectType),
+			Description: builtinDescriptions[builtin.projectType],
+			Source:      OriginBuiltin,
+			Detect:      builtin.detect,
+			Config:      GetTemplate(builtin.projectType),
+		})
+	}
+	return append(templates, Template{
+		Name:        string(ProjectTypeDefault),
+		Description: builtinDescriptions[ProjectTypeDefault],
+		Source:      OriginBuiltin,
+		Config:      GetDefaultConfig(),
+	})
+}
+
+// TemplateDirs returns the directories searched for user templates, from
+// lowest to highest precedence
+func TemplateDirs(repoDir string) []string {
+	var dirs []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(homeDir, GlobalConfigDir, TemplatesDirName))
+	}
+	return append(dirs, filepath.Join(repoDir, filepath.FromSlash(RepoTemplatesDirName)))
+}
+
+// LoadTemplates returns the built-in templates and the user templates found
+// in TemplateDirs. A user template replaces a template of the same name from
+// a lower-precedence source; new templates are added after the built-in ones.
+func LoadTemplates(repoDir string) ([]Template, error) {
+	templates := BuiltinTemplates()
+
+	for _, dir := range TemplateDirs(repoDir) {
+		files, err := templateFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			template, err := LoadTemplateFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			replaced := false
+			for i := range templates {
+				if templates[i].Name == template.Name {
+					templates[i] = *template
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				templates = append(templates, *template)
+			}
+		}
+	}
+
+	return templates, nil
+}
+
+// templateFiles lists the template files in dir in lexical order
+func templateFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadTemplateFile reads and validates a user template. The template is
+// named after the file unless it sets name.
+func LoadTemplateFile(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file: %w", err)
+	}
+
+	if err := ValidateTemplate(path, data); err != nil {
+		return nil, err
+	}
+
+	var file templateFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse template file %s: %w", path, err)
+	}
+
+	name := file.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	cfg := file.Config
+	return &Template{
+		Name:        name,
+		Description: file.Description,
+		Source:      path,
+		Detect:      file.Detect,
+		Config:      &cfg,
+	}, nil
+}
+
+// FindTemplate returns the template with the given name
+func FindTemplate(templates []Template, name string) (Template, error) {
+	for _, template := range templates {
+		if template.Name == name {
+			return template, nil
+		}
+	}
+
+	names := make([]string, len(templates))
+	for i, template := range templates {
+		names[i] = template.Name
+	}
+	return Template{}, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// DetectTemplates returns the templates whose detection rules match dir
+func DetectTemplates(templates []Template, dir string) []Template {
+	var detected []Template
+	for _, template := range templates {
+		if template.Detect.Matches(dir) {
+			detected = append(detected, template)
+		}
+	}
+	return detected
+}
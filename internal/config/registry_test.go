@@ -0,0 +1,99 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	homeDir := t.TempDir()
+	repoDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("USERPROFILE", homeDir)
+
+	globalDir := filepath.Join(homeDir, GlobalConfigDir, TemplatesDirName)
+	writeConfig(t, filepath.Join(globalDir, "django.yml"), `description: Django app
+detect:
+  any: [manage.py]
+resources:
+  symlink: [.venv]
+  copy: [.env]
+`)
+	writeConfig(t, filepath.Join(globalDir, "notes.txt"), "not a template")
+	writeConfig(t, filepath.Join(repoDir, ".gws", "templates", "node.yaml"), `description: Our Node setup
+detect:
+  all: [package.json, .nvmrc]
+resources:
+  symlink: [node_modules]
+hooks:
+  post_create: npm ci
+`)
+	writeConfig(t, filepath.Join(repoDir, ".gws", "templates", "legacy.yml"), `name: php-legacy
+resources:
+  symlink: [vendor]
+`)
+
+	templates, err := LoadTemplates(repoDir)
+	if err != nil {
+		t.Fatalf("failed to load templates: %v", err)
+	}
+
+	django, err := FindTemplate(templates, "django")
+	if err != nil {
+		t.Fatalf("expected django template: %v", err)
+	}
+	if django.Description != "Django app" || !reflect.DeepEqual(django.Config.Resources.Symlink, []string{".venv"}) {
+		t.Errorf("unexpected django template: %+v", django)
+	}
+
+	node, err := FindTemplate(templates, "node")
+	if err != nil {
+		t.Fatalf("expected node template: %v", err)
+	}
+	if node.Source != filepath.Join(repoDir, ".gws", "templates", "node.yaml") {
+		t.Errorf("expected repo template to replace built-in node, got source %s", node.Source)
+	}
+	if templates[0].Name != "node" {
+		t.Errorf("expected replaced template to keep its position, got %s", templates[0].Name)
+	}
+
+	if _, err := FindTemplate(templates, "php-legacy"); err != nil {
+		t.Errorf("expected template named by its name field: %v", err)
+	}
+
+	_, err = FindTemplate(templates, "cobol")
+	if err == nil || !strings.Contains(err.Error(), `unknown template "cobol"`) {
+		t.Errorf("expected unknown template error, got %v", err)
+	}
+
+	writeConfig(t, filepath.Join(repoDir, "package.json"), "{}")
+	writeConfig(t, filepath.Join(repoDir, "manage.py"), "")
+	var detected []string
+	for _, template := range DetectTemplates(templates, repoDir) {
+		detected = append(detected, template.Name)
+	}
+	if !reflect.DeepEqual(detected, []string{"django"}) {
+		t.Errorf("expected only django to be detected, got %v", detected)
+	}
+
+	cfg := MergeTemplates(DetectTemplates(templates, repoDir))
+	if cfg.WorktreePath != "../{branch}" {
+		t.Errorf("expected default worktree path, got %s", cfg.WorktreePath)
+	}
+}
+
+func TestLoadTemplatesInvalid(t *testing.T) {
+	homeDir := t.TempDir()
+	repoDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("USERPROFILE", homeDir)
+
+	writeConfig(t, filepath.Join(repoDir, ".gws", "templates", "bad.yml"), "detect:\n  files: [x]\n")
+
+	_, err := LoadTemplates(repoDir)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "detect.files"`) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
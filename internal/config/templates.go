@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path"
 	"path/filepath"
+
+	"github.com/fs0414/git-worktree-sync/internal/glob"
 )
 
 // ProjectType represents a project type
@@ -13,6 +16,13 @@ const (
 	ProjectTypeRails   ProjectType = "rails"
 	ProjectTypeGo      ProjectType = "go"
 	ProjectTypeRust    ProjectType = "rust"
+	ProjectTypePython  ProjectType = "python"
+	ProjectTypeGradle  ProjectType = "gradle"
+	ProjectTypeMaven   ProjectType = "maven"
+	ProjectTypeDotnet  ProjectType = "dotnet"
+	ProjectTypePHP     ProjectType = "php"
+	ProjectTypeElixir  ProjectType = "elixir"
+	ProjectTypeFlutter ProjectType = "flutter"
 	ProjectTypeDefault ProjectType = "default"
 )
 
@@ -27,6 +37,20 @@ func GetTemplate(projectType ProjectType) *Config {
 		return getGoTemplate()
 	case ProjectTypeRust:
 		return getRustTemplate()
+	case ProjectTypePython:
+		return getPythonTemplate()
+	case ProjectTypeGradle:
+		return getGradleTemplate()
+	case ProjectTypeMaven:
+		return getMavenTemplate()
+	case ProjectTypeDotnet:
+		return getDotnetTemplate()
+	case ProjectTypePHP:
+		return getPHPTemplate()
+	case ProjectTypeElixir:
+		return getElixirTemplate()
+	case ProjectTypeFlutter:
+		return getFlutterTemplate()
 	default:
 		return GetDefaultConfig()
 	}
@@ -98,28 +122,239 @@ func getRustTemplate() *Config {
 	}
 }
 
-// DetectProjectType attempts to detect the project type based on files in the directory
-func DetectProjectType(dir string) ProjectType {
-	// Check for Node.js
-	if fileExists(filepath.Join(dir, "package.json")) {
-		return ProjectTypeNode
+func getPythonTemplate() *Config {
+	return &Config{
+		Resources: Resources{
+			Symlink: []string{
+				".venv",
+				".tox",
+			},
+			Copy: []string{
+				".env",
+			},
+		},
+		WorktreePath: "../{branch}",
+		Exclude:      []string{"*.log", "*.pyc", "__pycache__"},
 	}
+}
 
-	// Check for Rails
-	if fileExists(filepath.Join(dir, "Gemfile")) && fileExists(filepath.Join(dir, "config", "application.rb")) {
-		return ProjectTypeRails
+func getGradleTemplate() *Config {
+	return &Config{
+		Resources: Resources{
+			Symlink: []string{
+				".gradle",
+				"build",
+			},
+			Copy: []string{
+				"local.properties",
+				".env",
+			},
+		},
+		WorktreePath: "../{branch}",
+		Exclude:      []string{"*.log"},
 	}
+}
 
-	// Check for Go
-	if fileExists(filepath.Join(dir, "go.mod")) {
-		return ProjectTypeGo
+func getMavenTemplate() *Config {
+	return &Config{
+		Resources: Resources{
+			Symlink: []string{
+				"target",
+			},
+			Copy: []string{
+				".env",
+			},
+		},
+		WorktreePath: "../{branch}",
+		Exclude:      []string{"*.log"},
 	}
+}
 
-	// Check for Rust
-	if fileExists(filepath.Join(dir, "Cargo.toml")) {
-		return ProjectTypeRust
+func getDotnetTemplate() *Config {
+	return &Config{
+		Resources: Resources{
+			Symlink: []string{
+				"bin",
+				"obj",
+			},
+			Copy: []string{
+				"appsettings.Development.json",
+				".env",
+			},
+		},
+		WorktreePath: "../{branch}",
+		Exclude:      []string{"*.log"},
 	}
+}
 
+func getPHPTemplate() *Config {
+	return &Config{
+		Resources: Resources{
+			Symlink: []string{
+				"vendor",
+			},
+			Copy: []string{
+				".env",
+			},
+		},
+		WorktreePath: "../{branch}",
+		Exclude:      []string{"*.log"},
+	}
+}
+
+func getElixirTemplate() *Config {
+	return &Config{
+		Resources: Resources{
+			Symlink: []string{
+				"deps",
+				"_build",
+			},
+			Copy: []string{
+				".env",
+			},
+		},
+		WorktreePath: "../{branch}",
+		Exclude:      []string{"*.log", "erl_crash.dump"},
+	}
+}
+
+func getFlutterTemplate() *Config {
+	return &Config{
+		Resources: Resources{
+			Symlink: []string{
+				".dart_tool",
+				"build",
+			},
+			Copy: []string{
+				".env",
+			},
+		},
+		WorktreePath: "../{branch}",
+		Exclude:      []string{"*.log"},
+	}
+}
+
+// MergeTemplates combines several templates into one configuration.
+// Resources and exclude patterns are merged in order without duplicates;
+// the worktree path and hooks of earlier templates take precedence.
+func MergeTemplates(templates []Template) *Config {
+	cfg := &Config{}
+	for _, template := range templates {
+		if cfg.WorktreePath == "" {
+			cfg.WorktreePath = template.Config.WorktreePath
+		}
+		cfg.AddResources(template.Config.Resources)
+		for _, pattern := range template.Config.Exclude {
+			if !contains(cfg.Exclude, pattern) {
+				cfg.Exclude = append(cfg.Exclude, pattern)
+			}
+		}
+		for name, command := range template.Config.Hooks {
+			if _, ok := cfg.Hooks[name]; !ok {
+				if cfg.Hooks == nil {
+					cfg.Hooks = make(map[string]string)
+				}
+				cfg.Hooks[name] = command
+			}
+		}
+	}
+
+	if cfg.WorktreePath == "" {
+		cfg.WorktreePath = "../{branch}"
+	}
+	return cfg
+}
+
+// Detection identifies a project by the files at its root
+type Detection struct {
+	// Any lists files of which at least one must exist
+	Any []string `yaml:"any,omitempty"`
+	// All lists files that must all exist
+	All []string `yaml:"all,omitempty"`
+}
+
+// IsEmpty reports whether the detection has no rules
+func (d Detection) IsEmpty() bool {
+	return len(d.Any) == 0 && len(d.All) == 0
+}
+
+// Matches reports whether the files in dir satisfy the detection rules.
+// The last path segment of each file may be a glob pattern such as "*.csproj".
+func (d Detection) Matches(dir string) bool {
+	if d.IsEmpty() {
+		return false
+	}
+	for _, file := range d.All {
+		if !markerExists(dir, file) {
+			return false
+		}
+	}
+	if len(d.Any) == 0 {
+		return true
+	}
+	for _, file := range d.Any {
+		if markerExists(dir, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinDetections are checked in order; the first match is the primary project type
+var builtinDetections = []struct {
+	projectType ProjectType
+	detect      Detection
+}{
+	{ProjectTypeNode, Detection{Any: []string{"package.json", "pnpm-workspace.yaml"}}},
+	{ProjectTypeRails, Detection{All: []string{"Gemfile", "config/application.rb"}}},
+	{ProjectTypeGo, Detection{Any: []string{"go.mod", "go.work"}}},
+	{ProjectTypeRust, Detection{Any: []string{"Cargo.toml"}}},
+	{ProjectTypePython, Detection{Any: []string{"pyproject.toml", "requirements.txt", "setup.py", "setup.cfg", "Pipfile", "tox.ini"}}},
+	{ProjectTypeGradle, Detection{Any: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}}},
+	{ProjectTypeMaven, Detection{Any: []string{"pom.xml"}}},
+	{ProjectTypeDotnet, Detection{Any: []string{"*.sln", "*.csproj", "*.fsproj", "*.vbproj"}}},
+	{ProjectTypePHP, Detection{Any: []string{"composer.json"}}},
+	{ProjectTypeElixir, Detection{Any: []string{"mix.exs"}}},
+	{ProjectTypeFlutter, Detection{Any: []string{"pubspec.yaml"}}},
+}
+
+// markerExists reports whether a marker file exists in dir. The last path
+// segment of marker may be a glob pattern.
+func markerExists(dir, marker string) bool {
+	if !glob.HasMeta(marker) {
+		return fileExists(filepath.Join(dir, filepath.FromSlash(marker)))
+	}
+
+	parent, pattern := path.Split(marker)
+	entries, err := os.ReadDir(filepath.Join(dir, filepath.FromSlash(parent)))
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if matched, _ := path.Match(pattern, entry.Name()); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectProjectTypes returns every built-in project type detected in the
+// directory, in order of builtinDetections
+func DetectProjectTypes(dir string) []ProjectType {
+	var projectTypes []ProjectType
+	for _, builtin := range builtinDetections {
+		if builtin.detect.Matches(dir) {
+			projectTypes = append(projectTypes, builtin.projectType)
+		}
+	}
+	return projectTypes
+}
+
+// DetectProjectType attempts to detect the project type based on files in the directory
+func DetectProjectType(dir string) ProjectType {
+	if projectTypes := DetectProjectTypes(dir); len(projectTypes) > 0 {
+		return projectTypes[0]
+	}
 	return ProjectTypeDefault
 }
 
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -32,6 +33,41 @@ func TestDetectProjectType(t *testing.T) {
 			files:    []string{"Gemfile", "config/application.rb"},
 			expected: ProjectTypeRails,
 		},
+		{
+			name:     "Python project",
+			files:    []string{"pyproject.toml"},
+			expected: ProjectTypePython,
+		},
+		{
+			name:     "Gradle project",
+			files:    []string{"settings.gradle.kts"},
+			expected: ProjectTypeGradle,
+		},
+		{
+			name:     "Maven project",
+			files:    []string{"pom.xml"},
+			expected: ProjectTypeMaven,
+		},
+		{
+			name:     ".NET project",
+			files:    []string{"App.sln"},
+			expected: ProjectTypeDotnet,
+		},
+		{
+			name:     "PHP project",
+			files:    []string{"composer.json"},
+			expected: ProjectTypePHP,
+		},
+		{
+			name:     "Elixir project",
+			files:    []string{"mix.exs"},
+			expected: ProjectTypeElixir,
+		},
+		{
+			name:     "Flutter project",
+			files:    []string{"pubspec.yaml"},
+			expected: ProjectTypeFlutter,
+		},
 		{
 			name:     "Default project",
 			files:    []string{},
@@ -77,6 +113,13 @@ func TestGetTemplate(t *testing.T) {
 		ProjectTypeRails,
 		ProjectTypeGo,
 		ProjectTypeRust,
+		ProjectTypePython,
+		ProjectTypeGradle,
+		ProjectTypeMaven,
+		ProjectTypeDotnet,
+		ProjectTypePHP,
+		ProjectTypeElixir,
+		ProjectTypeFlutter,
 		ProjectTypeDefault,
 	}
 
@@ -100,3 +143,30 @@ func TestGetTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestDetectProjectTypes(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, file := range []string{"Gemfile", "config/application.rb", "package.json", "requirements.txt"} {
+		writeConfig(t, filepath.Join(tmpDir, file), "")
+	}
+
+	projectTypes := DetectProjectTypes(tmpDir)
+	expected := []ProjectType{ProjectTypeNode, ProjectTypeRails, ProjectTypePython}
+	if !reflect.DeepEqual(projectTypes, expected) {
+		t.Fatalf("expected %v, got %v", expected, projectTypes)
+	}
+
+	cfg := MergeTemplates(DetectTemplates(BuiltinTemplates(), tmpDir))
+	expectedSymlink := []string{"node_modules", ".pnpm-store", "dist", "vendor", "tmp", ".venv", ".tox"}
+	if !reflect.DeepEqual(cfg.Resources.Symlink, expectedSymlink) {
+		t.Errorf("expected symlink %v, got %v", expectedSymlink, cfg.Resources.Symlink)
+	}
+	expectedCopy := []string{".env", ".env.local", "config/master.key"}
+	if !reflect.DeepEqual(cfg.Resources.Copy, expectedCopy) {
+		t.Errorf("expected copy %v, got %v", expectedCopy, cfg.Resources.Copy)
+	}
+	expectedExclude := []string{"*.log", "tmp/*", "*.pyc", "__pycache__"}
+	if !reflect.DeepEqual(cfg.Exclude, expectedExclude) {
+		t.Errorf("expected exclude %v, got %v", expectedExclude, cfg.Exclude)
+	}
+}
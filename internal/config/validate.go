@@ -0,0 +1,348 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/glob"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem found in a config file
+type ValidationError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors is the list of problems found in a config file
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "invalid configuration:\n  " + strings.Join(messages, "\n  ")
+}
+
+// Known keys of each section of a config file
+var (
+	topLevelKeys = []string{"resources", "worktree_path", "exclude", "hooks", "merge", "package_manager", "resource_options"}
+	resourceKeys = []string{"symlink", "copy"}
+	// templateKeys are the additional top-level keys of template files
+	templateKeys = []string{"name", "description", "detect"}
+	detectKeys   = []string{"any", "all"}
+	optionKeys   = []string{"lockfiles", "on_lockfile_change", "install", "copy_strategy"}
+	hookKeys     = []string{"pre_create", "post_create", "pre_sync", "post_sync", "pre_remove"}
+)
+
+// Validate checks the contents of a config file against the schema. It
+// rejects unknown keys, wrongly typed values, resources that escape the
+// worktree, resources listed more than once and invalid exclude patterns.
+func Validate(filePath string, data []byte) error {
+	return validate(&validator{path: filePath}, data)
+}
+
+// ValidateTemplate checks the contents of a template file. Templates are
+// config files that may also set name, description and detect.
+func ValidateTemplate(filePath string, data []byte) error {
+	return validate(&validator{path: filePath, template: true}, data)
+}
+
+func validate(v *validator, data []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", v.path, err)
+	}
+
+	// Empty files are valid
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	v.validateTopLevel(root.Content[0])
+
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+// validator collects validation errors for a single file
+type validator struct {
+	path     string
+	template bool
+	errors   ValidationErrors
+}
+
+func (v *validator) addError(node *yaml.Node, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{
+		Path:    v.path,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// expect reports an error if node is not of the given kind
+func (v *validator) expect(node *yaml.Node, kind yaml.Kind, name, description string) bool {
+	if node.Kind == kind {
+		return true
+	}
+	// An empty value (e.g. "copy:" with no items) is treated as unset
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return false
+	}
+	v.addError(node, "%s must be %s", name, description)
+	return false
+}
+
+// fields iterates over a mapping, reporting unknown keys
+func (v *validator) fields(mapping *yaml.Node, section string, known []string, fn func(key string, keyNode, value *yaml.Node)) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, value := mapping.Content[i], mapping.Content[i+1]
+		key := keyNode.Value
+
+		if !contains(known, key) {
+			name := key
+			if section != "" {
+				name = section + "." + key
+			}
+			if suggestion := suggest(key, known); suggestion != "" {
+				v.addError(keyNode, "unknown field %q (did you mean %q?)", name, suggestion)
+			} else {
+				v.addError(keyNode, "unknown field %q", name)
+			}
+			continue
+		}
+
+		fn(key, keyNode, value)
+	}
+}
+
+func (v *validator) validateTopLevel(node *yaml.Node) {
+	if !v.expect(node, yaml.MappingNode, "config", "a mapping") {
+		return
+	}
+
+	known := topLevelKeys
+	if v.template {
+		known = append(append([]string{}, topLevelKeys...), templateKeys...)
+	}
+
+	v.fields(node, "", known, func(key string, keyNode, value *yaml.Node) {
+		switch key {
+		case "name", "description":
+			v.expect(value, yaml.ScalarNode, key, "a string")
+		case "detect":
+			if v.expect(value, yaml.MappingNode, key, "a mapping") {
+				v.fields(value, key, detectKeys, func(key string, keyNode, value *yaml.Node) {
+					for _, item := range v.stringList(value, "detect."+key) {
+						if err := glob.Validate(item.Value); err != nil {
+							v.addError(item, "invalid detect pattern: %v", err)
+						}
+					}
+				})
+			}
+		case "resources":
+			v.validateResources(value)
+		case "worktree_path":
+			v.expect(value, yaml.ScalarNode, key, "a string")
+		case "exclude":
+			for _, item := range v.stringList(value, key) {
+				if err := glob.Validate(item.Value); err != nil {
+					v.addError(item, "invalid exclude pattern: %v", err)
+				}
+			}
+		case "hooks":
+			if v.expect(value, yaml.MappingNode, key, "a mapping of hook names to commands") {
+				v.fields(value, key, hookKeys, func(key string, keyNode, value *yaml.Node) {
+					v.expect(value, yaml.ScalarNode, "hooks."+key, "a command string")
+				})
+			}
+		case "merge":
+			if v.expect(value, yaml.ScalarNode, key, "a string") && value.Value != MergeAppend && value.Value != MergeReplace {
+				v.addError(value, "merge must be %q or %q, got %q", MergeAppend, MergeReplace, value.Value)
+			}
+		case "resource_options":
+			v.validateResourceOptions(value)
+		case "package_manager":
+			if v.expect(value, yaml.ScalarNode, key, "a string") && !contains(PackageManagers, value.Value) {
+				v.addError(value, "package_manager must be one of %s, got %q", strings.Join(PackageManagers, ", "), value.Value)
+			}
+		}
+	})
+}
+
+func (v *validator) validateResources(node *yaml.Node) {
+	if !v.expect(node, yaml.MappingNode, "resources", "a mapping") {
+		return
+	}
+
+	seen := make(map[string]string)
+	v.fields(node, "resources", resourceKeys, func(key string, keyNode, value *yaml.Node) {
+		name := "resources." + key
+		for _, item := range v.stringList(value, name) {
+			if msg := checkResourcePath(item.Value); msg != "" {
+				v.addError(item, "%s: %s", name, msg)
+				continue
+			}
+			if glob.HasMeta(item.Value) {
+				if err := glob.Validate(item.Value); err != nil || strings.HasPrefix(item.Value, "!") {
+					v.addError(item, "%s: invalid pattern %q", name, item.Value)
+					continue
+				}
+			}
+
+			cleaned := path.Clean(item.Value)
+			if previous, ok := seen[cleaned]; ok {
+				v.addError(item, "resource %q is listed more than once (also in %s)", item.Value, previous)
+				continue
+			}
+			seen[cleaned] = name
+		}
+	})
+}
+
+func (v *validator) validateResourceOptions(node *yaml.Node) {
+	if !v.expect(node, yaml.MappingNode, "resource_options", "a mapping of resources to options") {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, value := node.Content[i], node.Content[i+1]
+		resource := keyNode.Value
+		if msg := checkResourcePath(resource); msg != "" {
+			v.addError(keyNode, "resource_options: %s", msg)
+			continue
+		}
+
+		section := "resource_options." + resource
+		if !v.expect(value, yaml.MappingNode, section, "a mapping") {
+			continue
+		}
+
+		var action, install *yaml.Node
+		v.fields(value, section, optionKeys, func(key string, keyNode, value *yaml.Node) {
+			switch key {
+			case "lockfiles":
+				for _, item := range v.stringList(value, section+".lockfiles") {
+					if msg := checkResourcePath(item.Value); msg != "" {
+						v.addError(item, "%s.lockfiles: %s", section, strings.Replace(msg, "resource", "lockfile", 1))
+					}
+				}
+			case "on_lockfile_change":
+				if v.expect(value, yaml.ScalarNode, section+"."+key, "a string") {
+					action = value
+				}
+			case "install":
+				if v.expect(value, yaml.ScalarNode, section+"."+key, "a command string") {
+					install = value
+				}
+			case "copy_strategy":
+				if v.expect(value, yaml.ScalarNode, section+"."+key, "a string") && !contains(CopyStrategies, value.Value) {
+					v.addError(value, "%s.copy_strategy must be one of %s, got %q", section, strings.Join(CopyStrategies, ", "), value.Value)
+				}
+			}
+		})
+
+		if action == nil {
+			continue
+		}
+		actions := []string{LockfileWarn, LockfileCopy, LockfileHook}
+		if !contains(actions, action.Value) {
+			v.addError(action, "%s.on_lockfile_change must be one of %s, got %q", section, strings.Join(actions, ", "), action.Value)
+		} else if action.Value == LockfileHook && (install == nil || strings.TrimSpace(install.Value) == "") {
+			v.addError(action, "%s.on_lockfile_change is %q but no install command is set", section, LockfileHook)
+		}
+	}
+}
+
+// stringList returns the items of a list of strings, reporting other values
+func (v *validator) stringList(node *yaml.Node, name string) []*yaml.Node {
+	if !v.expect(node, yaml.SequenceNode, name, "a list") {
+		return nil
+	}
+
+	var items []*yaml.Node
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode || item.Tag == "!!null" {
+			v.addError(item, "%s items must be strings", name)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// checkResourcePath returns a message if a resource path is unusable
+func checkResourcePath(resource string) string {
+	slashed := strings.ReplaceAll(resource, `\`, "/")
+	switch {
+	case strings.TrimSpace(resource) == "":
+		return "resource path is empty"
+	case path.IsAbs(slashed) || (len(slashed) > 1 && slashed[1] == ':'):
+		return fmt.Sprintf("resource %q must be relative to the worktree", resource)
+	case strings.HasPrefix(slashed, "~"):
+		return fmt.Sprintf("resource %q must be relative to the worktree", resource)
+	}
+
+	cleaned := path.Clean(slashed)
+	if cleaned == "." {
+		return fmt.Sprintf("resource %q refers to the worktree root", resource)
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Sprintf("resource %q escapes the worktree", resource)
+	}
+	return ""
+}
+
+// suggest returns the known key closest to key, if it is close enough to be a typo
+func suggest(key string, known []string) string {
+	best := ""
+	bestDistance := len(key)/3 + 2
+
+	candidates := append([]string{}, known...)
+	sort.Strings(candidates)
+	for _, candidate := range candidates {
+		if d := levenshtein(key, candidate); d < bestDistance {
+			best = candidate
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name: "valid",
+			content: `resources:
+  symlink:
+    - node_modules
+  copy:
+    - .env
+    - config/master.key
+worktree_path: "../{branch}"
+exclude:
+  - "*.log"
+  - "**/cache/"
+hooks:
+  post_create: npm install
+merge: replace
+package_manager: pnpm
+`,
+		},
+		{
+			name:    "empty file",
+			content: "",
+		},
+		{
+			name:    "empty lists",
+			content: "resources:\n  symlink:\n  copy:\n",
+		},
+		{
+			name:    "unknown top-level field",
+			content: "resources:\n  symlink: [a]\nworktree-path: ../x\n",
+			want:    []string{`3:1: unknown field "worktree-path" (did you mean "worktree_path"?)`},
+		},
+		{
+			name:    "unknown resources field",
+			content: "resources:\n  symlinks:\n    - node_modules\n",
+			want:    []string{`2:3: unknown field "resources.symlinks" (did you mean "symlink"?)`},
+		},
+		{
+			name:    "unknown field without suggestion",
+			content: "colour: blue\n",
+			want:    []string{`1:1: unknown field "colour"`},
+		},
+		{
+			name:    "escaping resources",
+			content: "resources:\n  symlink:\n    - /etc/passwd\n    - ../shared\n    - a/../../b\n    - .\n",
+			want: []string{
+				`3:7: resources.symlink: resource "/etc/passwd" must be relative to the worktree`,
+				`4:7: resources.symlink: resource "../shared" escapes the worktree`,
+				`5:7: resources.symlink: resource "a/../../b" escapes the worktree`,
+				`6:7: resources.symlink: resource "." refers to the worktree root`,
+			},
+		},
+		{
+			name:    "duplicate resources",
+			content: "resources:\n  symlink: [vendor, .env]\n  copy:\n    - ./.env\n",
+			want:    []string{`4:7: resource "./.env" is listed more than once (also in resources.symlink)`},
+		},
+		{
+			name:    "resource patterns",
+			content: "resources:\n  symlink:\n    - \"**/node_modules\"\n    - \"packages/*/[a-\"\n    - \"../**/x\"\n",
+			want: []string{
+				`4:7: resources.symlink: invalid pattern "packages/*/[a-"`,
+				`5:7: resources.symlink: resource "../**/x" escapes the worktree`,
+			},
+		},
+		{
+			name:    "invalid exclude",
+			content: "exclude:\n  - \"[a-\"\n  - \"foo**\"\n",
+			want:    []string{"2:5: invalid exclude pattern", "3:5: invalid exclude pattern"},
+		},
+		{
+			name:    "wrong types",
+			content: "resources: [node_modules]\nexclude: \"*.log\"\nhooks:\n  post_create: [a, b]\nmerge: prepend\npackage_manager: cargo\n",
+			want: []string{
+				"1:12: resources must be a mapping",
+				"2:10: exclude must be a list",
+				"4:16: hooks.post_create must be a command string",
+				`5:8: merge must be "append" or "replace", got "prepend"`,
+				`6:18: package_manager must be one of auto, none, npm, pnpm, yarn, yarn-pnp, bun, got "cargo"`,
+			},
+		},
+		{
+			name:    "unknown hooks",
+			content: "hooks:\n  post_create: npm install\n  pre_sycn: make\n  on_create: make\n",
+			want: []string{
+				`3:3: unknown field "hooks.pre_sycn" (did you mean "pre_sync"?)`,
+				`4:3: unknown field "hooks.on_create"`,
+			},
+		},
+		{
+			name: "resource options",
+			content: `resource_options:
+  vendor:
+    lockfiles: [Gemfile.lock]
+    on_lockfile_change: hook
+    install: bundle install
+    copy_strategy: clone
+  node_modules:
+    lockfiles: [../package-lock.json]
+    on_lockfile_change: rebuild
+  .venv:
+    on_lockfile_change: hook
+    installs: pip install
+`,
+			want: []string{
+				`6:20: resource_options.vendor.copy_strategy must be one of auto, reflink, hardlink, copy, got "clone"`,
+				`8:17: resource_options.node_modules.lockfiles: lockfile "../package-lock.json" escapes the worktree`,
+				`9:25: resource_options.node_modules.on_lockfile_change must be one of warn, copy, hook, got "rebuild"`,
+				`12:5: unknown field "resource_options..venv.installs" (did you mean "install"?)`,
+				`11:25: resource_options..venv.on_lockfile_change is "hook" but no install command is set`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(ConfigFileName, []byte(tt.content))
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var problems ValidationErrors
+			if !errors.As(err, &problems) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("expected %d problems, got %v", len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if got := problems[i].Error(); !strings.HasPrefix(got, ConfigFileName+":"+want) {
+					t.Errorf("problem %d = %q, want prefix %q", i, got, ConfigFileName+":"+want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeConfig(t, filepath.Join(tmpDir, ConfigFileName), "resources:\n  symlinks:\n    - node_modules\n")
+
+	_, err := Load(tmpDir)
+	if err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+	if !strings.Contains(err.Error(), `did you mean "symlink"?`) {
+		t.Errorf("expected suggestion in error, got %v", err)
+	}
+}
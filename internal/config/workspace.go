@@ -0,0 +1,411 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/glob"
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace tools detected by DetectWorkspaces
+const (
+	WorkspacePnpm  = "pnpm"
+	WorkspaceNpm   = "npm"
+	WorkspaceGo    = "go"
+	WorkspaceCargo = "cargo"
+)
+
+// Workspace is a monorepo declared by a workspace manifest at the repository root
+type Workspace struct {
+	// Tool is the tool that declares the workspace (pnpm, npm, go, cargo)
+	Tool string
+	// Type is the project type of the members
+	Type ProjectType
+	// Members are the member directories as slash-separated paths relative
+	// to the repository root, in lexical order
+	Members []string
+}
+
+// workspaceDetector reads the member patterns of one kind of workspace
+type workspaceDetector struct {
+	tool        string
+	projectType ProjectType
+	// marker is the file that identifies a member directory
+	marker string
+	// patterns returns the member patterns, or nil if the workspace is not declared
+	patterns func(dir string) ([]string, error)
+}
+
+var workspaceDetectors = []workspaceDetector{
+	{WorkspacePnpm, ProjectTypeNode, "package.json", pnpmWorkspacePatterns},
+	{WorkspaceNpm, ProjectTypeNode, "package.json", packageJSONWorkspacePatterns},
+	{WorkspaceGo, ProjectTypeGo, "go.mod", goWorkPatterns},
+	{WorkspaceCargo, ProjectTypeRust, "Cargo.toml", cargoWorkspacePatterns},
+}
+
+// DetectWorkspaces reads the workspace manifests in dir: pnpm-workspace.yaml,
+// the "workspaces" field of package.json, go.work and the [workspace] table
+// of Cargo.toml. pnpm and npm/yarn workspaces are not reported together.
+func DetectWorkspaces(dir string) ([]Workspace, error) {
+	var workspaces []Workspace
+	for _, d := range workspaceDetectors {
+		if d.projectType == ProjectTypeNode && hasWorkspaceType(workspaces, ProjectTypeNode) {
+			continue
+		}
+
+		patterns, err := d.patterns(dir)
+		if err != nil {
+			return nil, err
+		}
+		if patterns == nil {
+			continue
+		}
+
+		members, err := workspaceMembers(dir, patterns, d.marker)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, Workspace{Tool: d.tool, Type: d.projectType, Members: members})
+	}
+	return workspaces, nil
+}
+
+func hasWorkspaceType(workspaces []Workspace, projectType ProjectType) bool {
+	for _, ws := range workspaces {
+		if ws.Type == projectType {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkspaceResources returns resource entries for the dependency and build
+// directories of every workspace member. Dependency directories are always
+// listed; build directories only if they exist in the member. Cargo
+// workspaces share the root target directory, so their members add nothing.
+// pnpm node_modules cannot be linked, so pnpm members add no node_modules.
+func WorkspaceResources(dir string, ws Workspace) Resources {
+	var dependencies, builds []string
+	switch ws.Type {
+	case ProjectTypeNode:
+		if ws.Tool != WorkspacePnpm {
+			dependencies = []string{"node_modules"}
+		}
+		builds = []string{"dist", "build", ".next", ".turbo"}
+	case ProjectTypeGo:
+		builds = []string{"vendor"}
+	}
+
+	var resources Resources
+	for _, member := range ws.Members {
+		if member == "." {
+			continue
+		}
+		for _, name := range dependencies {
+			resources.Symlink = append(resources.Symlink, member+"/"+name)
+		}
+		for _, name := range builds {
+			if fileExists(filepath.Join(dir, filepath.FromSlash(member), name)) {
+				resources.Symlink = append(resources.Symlink, member+"/"+name)
+			}
+		}
+	}
+	return resources
+}
+
+// workspaceMembers resolves member patterns to the directories that contain
+// marker. Patterns prefixed with "!" exclude members.
+func workspaceMembers(dir string, patterns []string, marker string) ([]string, error) {
+	var include, exclude []string
+	hasMeta := false
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		p = path.Clean(strings.TrimPrefix(p, "!"))
+		// Members outside the workspace, e.g. "use ../shared" in go.work,
+		// are not part of the worktree
+		if p == ".." || strings.HasPrefix(p, "../") || path.IsAbs(p) {
+			continue
+		}
+		if negated {
+			exclude = append(exclude, p)
+			continue
+		}
+		if err := glob.Validate(p); err != nil {
+			return nil, fmt.Errorf("invalid workspace member pattern: %w", err)
+		}
+		hasMeta = hasMeta || glob.HasMeta(p)
+		include = append(include, p)
+	}
+
+	isMember := func(rel string) bool {
+		if !fileExists(filepath.Join(dir, filepath.FromSlash(rel), marker)) {
+			return false
+		}
+		return matchAnchored(include, rel) && !matchAnchored(exclude, rel)
+	}
+
+	var members []string
+	if !hasMeta {
+		for _, p := range include {
+			if isMember(p) && !contains(members, p) {
+				members = append(members, p)
+			}
+		}
+		sort.Strings(members)
+		return members, nil
+	}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != dir && skipWorkspaceDir(d.Name()) {
+			return fs.SkipDir
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && isMember(rel) {
+			members = append(members, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workspace members: %w", err)
+	}
+
+	return members, nil
+}
+
+// skipWorkspaceDir reports whether a directory cannot contain workspace members
+func skipWorkspaceDir(name string) bool {
+	switch name {
+	case ".git", "node_modules", "target", "vendor":
+		return true
+	}
+	return false
+}
+
+// matchAnchored reports whether rel matches any of the patterns, which are
+// relative to the repository root
+func matchAnchored(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if p == rel {
+			return true
+		}
+		if matched, _ := glob.Match("/"+p, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// pnpmWorkspacePatterns reads the packages list of pnpm-workspace.yaml
+func pnpmWorkspacePatterns(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "pnpm-workspace.yaml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pnpm-workspace.yaml: %w", err)
+	}
+
+	var manifest struct {
+		Packages []string `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse pnpm-workspace.yaml: %w", err)
+	}
+	return append([]string{}, manifest.Packages...), nil
+}
+
+// packageJSONWorkspacePatterns reads the workspaces field of package.json,
+// either a list of patterns or an object with a packages list (yarn)
+func packageJSONWorkspacePatterns(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+	if len(manifest.Workspaces) == 0 {
+		return nil, nil
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(manifest.Workspaces, &patterns); err == nil {
+		return append([]string{}, patterns...), nil
+	}
+
+	var object struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(manifest.Workspaces, &object); err != nil {
+		return nil, fmt.Errorf("failed to parse workspaces in package.json: %w", err)
+	}
+	return append([]string{}, object.Packages...), nil
+}
+
+// goWorkPatterns reads the use directives of go.work
+func goWorkPatterns(dir string) ([]string, error) {
+	file, err := os.Open(filepath.Join(dir, "go.work"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.work: %w", err)
+	}
+	defer file.Close()
+
+	patterns := []string{}
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			patterns = append(patterns, strings.Trim(fields[0], `"`))
+		case fields[0] == "use" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "use" && len(fields) > 1:
+			patterns = append(patterns, strings.Trim(fields[1], `"`))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go.work: %w", err)
+	}
+
+	return patterns, nil
+}
+
+// cargoWorkspacePatterns reads the members and exclude arrays of the
+// [workspace] table in Cargo.toml. Exclusions are returned prefixed with "!".
+func cargoWorkspacePatterns(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Cargo.toml: %w", err)
+	}
+
+	table, ok := tomlTable(string(data), "workspace")
+	if !ok {
+		return nil, nil
+	}
+
+	patterns := []string{}
+	patterns = append(patterns, tomlStringArray(table, "members")...)
+	for _, excluded := range tomlStringArray(table, "exclude") {
+		patterns = append(patterns, "!"+excluded)
+	}
+	return patterns, nil
+}
+
+// tomlTable returns the body of a [name] table. Only what is needed to read
+// Cargo workspaces is supported.
+func tomlTable(data, name string) (string, bool) {
+	var body strings.Builder
+	found := false
+	for _, line := range strings.Split(data, "\n") {
+		if isTOMLHeader(line) {
+			if found {
+				break
+			}
+			found = strings.TrimSpace(stripTOMLComment(line)) == "["+name+"]"
+			continue
+		}
+		if found {
+			body.WriteString(line)
+			body.WriteString("\n")
+		}
+	}
+	return body.String(), found
+}
+
+// isTOMLHeader reports whether a line is a table header such as [dependencies]
+func isTOMLHeader(line string) bool {
+	line = strings.TrimSpace(stripTOMLComment(line))
+	return strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") && !strings.ContainsAny(line, `"'`)
+}
+
+// tomlStringArray returns the strings of key = [ ... ] in a table body
+func tomlStringArray(table, key string) []string {
+	var values []string
+	inArray := false
+	for _, line := range strings.Split(table, "\n") {
+		line = stripTOMLComment(line)
+		if !inArray {
+			name, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(name) != key {
+				continue
+			}
+			line = strings.TrimSpace(value)
+			if !strings.HasPrefix(line, "[") {
+				continue
+			}
+			line = line[1:]
+			inArray = true
+		}
+
+		end := strings.Index(line, "]")
+		if end >= 0 {
+			line = line[:end]
+		}
+		for _, item := range strings.Split(line, ",") {
+			item = strings.Trim(strings.TrimSpace(item), `"'`)
+			if item != "" {
+				values = append(values, item)
+			}
+		}
+		if end >= 0 {
+			break
+		}
+	}
+	return values
+}
+
+// stripTOMLComment removes a trailing comment outside of quoted strings
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
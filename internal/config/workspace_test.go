@@ -0,0 +1,132 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDetectWorkspaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected []Workspace
+	}{
+		{
+			name: "pnpm",
+			files: map[string]string{
+				"package.json":                           "{}",
+				"pnpm-workspace.yaml":                    "packages:\n  - \"packages/*\"\n  - \"apps/**\"\n  - \"!**/fixtures/**\"\n",
+				"packages/a/package.json":                "{}",
+				"packages/b/package.json":                "{}",
+				"packages/notes.md":                      "",
+				"apps/web/package.json":                  "{}",
+				"apps/web/fixtures/x/package.json":       "{}",
+				"apps/web/node_modules/dep/package.json": "{}",
+			},
+			expected: []Workspace{{Tool: WorkspacePnpm, Type: ProjectTypeNode, Members: []string{"apps/web", "packages/a", "packages/b"}}},
+		},
+		{
+			name: "npm workspaces",
+			files: map[string]string{
+				"package.json":            `{"workspaces": ["packages/*"]}`,
+				"packages/a/package.json": "{}",
+			},
+			expected: []Workspace{{Tool: WorkspaceNpm, Type: ProjectTypeNode, Members: []string{"packages/a"}}},
+		},
+		{
+			name: "yarn workspaces object",
+			files: map[string]string{
+				"package.json":         `{"workspaces": {"packages": ["libs/*"]}}`,
+				"libs/ui/package.json": "{}",
+			},
+			expected: []Workspace{{Tool: WorkspaceNpm, Type: ProjectTypeNode, Members: []string{"libs/ui"}}},
+		},
+		{
+			name: "go.work",
+			files: map[string]string{
+				"go.work":         "go 1.22\n\nuse (\n\t./cmd/tool // the CLI\n\t./lib\n\t../shared\n)\nuse ./missing\n",
+				"cmd/tool/go.mod": "module tool\n",
+				"lib/go.mod":      "module lib\n",
+				// Outside the workspace, so never a member
+				"../shared/go.mod": "module shared\n",
+			},
+			expected: []Workspace{{Tool: WorkspaceGo, Type: ProjectTypeGo, Members: []string{"cmd/tool", "lib"}}},
+		},
+		{
+			name: "cargo",
+			files: map[string]string{
+				"Cargo.toml": `[workspace]
+members = [
+    "crates/*", # all crates
+    "tools/cli",
+]
+exclude = ["crates/old"]
+
+[workspace.dependencies]
+serde = "1"
+`,
+				"crates/core/Cargo.toml": "",
+				"crates/old/Cargo.toml":  "",
+				"tools/cli/Cargo.toml":   "",
+			},
+			expected: []Workspace{{Tool: WorkspaceCargo, Type: ProjectTypeRust, Members: []string{"crates/core", "tools/cli"}}},
+		},
+		{
+			name: "no workspace",
+			files: map[string]string{
+				"package.json": `{"name": "app"}`,
+				"Cargo.toml":   "[package]\nname = \"app\"\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for file, content := range tt.files {
+				writeConfig(t, filepath.Join(dir, file), content)
+			}
+
+			workspaces, err := DetectWorkspaces(dir)
+			if err != nil {
+				t.Fatalf("failed to detect workspaces: %v", err)
+			}
+			if !reflect.DeepEqual(workspaces, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, workspaces)
+			}
+		})
+	}
+}
+
+func TestWorkspaceResources(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, "packages/a/dist/index.js"), "")
+
+	ws := Workspace{Tool: WorkspaceNpm, Type: ProjectTypeNode, Members: []string{"packages/a", "packages/b"}}
+	resources := WorkspaceResources(dir, ws)
+
+	expected := []string{"packages/a/node_modules", "packages/a/dist", "packages/b/node_modules"}
+	if !reflect.DeepEqual(resources.Symlink, expected) {
+		t.Errorf("expected %v, got %v", expected, resources.Symlink)
+	}
+
+	// pnpm node_modules are never linked
+	pnpm := WorkspaceResources(dir, Workspace{Tool: WorkspacePnpm, Type: ProjectTypeNode, Members: ws.Members})
+	if expected := []string{"packages/a/dist"}; !reflect.DeepEqual(pnpm.Symlink, expected) {
+		t.Errorf("expected %v for pnpm, got %v", expected, pnpm.Symlink)
+	}
+
+	cfg := GetTemplate(ProjectTypeNode)
+	cfg.AddResources(resources)
+	cfg.AddResources(resources)
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	if err := Validate(ConfigFileName, data); err != nil {
+		t.Errorf("expected generated config to be valid: %v", err)
+	}
+}
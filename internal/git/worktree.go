@@ -10,9 +10,20 @@ import (
 
 // Worktree represents a git worktree
 type Worktree struct {
-	Path   string
-	Branch string
-	IsMain bool
+	Path string
+	// Branch is the checked out branch without the refs/heads/ prefix
+	// (e.g. "feature/auth"), empty when HEAD is detached
+	Branch   string
+	Head     string
+	IsMain   bool
+	Bare     bool
+	Detached bool
+	Locked   bool
+	// LockedReason is the reason given to 'git worktree lock', if any
+	LockedReason string
+	Prunable     bool
+	// PrunableReason explains why git considers the worktree prunable
+	PrunableReason string
 }
 
 // IsGitRepository checks if the current directory is a git repository
@@ -35,20 +46,62 @@ func GetMainWorktreePath(currentDir string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// CreateWorktree creates a new git worktree
-func CreateWorktree(branchName, path, baseBranch string) error {
-	// Check if path already exists
-	if _, err := os.Stat(path); err == nil {
-		return fmt.Errorf("path already exists: %s", path)
+// FormatCommand returns the git command line for args, quoting arguments
+// the shell would split
+func FormatCommand(args []string) string {
+	quoted := []string{"git"}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, arg)
 	}
+	return strings.Join(quoted, " ")
+}
 
-	var args []string
+// CreateWorktreeArgs returns the git arguments that add a worktree with a
+// new branch
+func CreateWorktreeArgs(branchName, path, baseBranch string) []string {
 	if baseBranch != "" {
 		// Create new branch from base branch
-		args = []string{"worktree", "add", "-b", branchName, path, baseBranch}
-	} else {
-		// Create new branch from current HEAD
-		args = []string{"worktree", "add", "-b", branchName, path}
+		return []string{"worktree", "add", "-b", branchName, path, baseBranch}
+	}
+	// Create new branch from current HEAD
+	return []string{"worktree", "add", "-b", branchName, path}
+}
+
+// CheckoutWorktreeArgs returns the git arguments that add a worktree for an
+// existing local branch
+func CheckoutWorktreeArgs(branchName, path string) []string {
+	return []string{"worktree", "add", path, branchName}
+}
+
+// TrackWorktreeArgs returns the git arguments that add a worktree with a new
+// local branch tracking a remote branch
+func TrackWorktreeArgs(branchName, path, remoteBranch string) []string {
+	return []string{"worktree", "add", "--track", "-b", branchName, path, remoteBranch}
+}
+
+// DetachedWorktreeArgs returns the git arguments that add a worktree with a
+// detached HEAD at commit
+func DetachedWorktreeArgs(path, commit string) []string {
+	return []string{"worktree", "add", "--detach", path, commit}
+}
+
+// CheckWorktreePath returns an error if a worktree cannot be added at path
+// because the path already exists
+func CheckWorktreePath(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("path already exists: %s", path)
+	}
+	return nil
+}
+
+// AddWorktree runs a 'git worktree add' command built by one of the
+// *WorktreeArgs functions, refusing to create the worktree over an existing path
+func AddWorktree(path string, args []string) error {
+	if err := CheckWorktreePath(path); err != nil {
+		return err
 	}
 
 	cmd := exec.Command("git", args...)
@@ -62,23 +115,36 @@ func CreateWorktree(branchName, path, baseBranch string) error {
 
 // ListWorktrees returns a list of all worktrees
 func ListWorktrees() ([]Worktree, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
+	// -z terminates fields with NUL so that paths containing newlines are safe
+	cmd := exec.Command("git", "worktree", "list", "--porcelain", "-z")
 	output, err := cmd.Output()
-	if err != nil {
+	if err == nil {
+		return parseWorktreeList(string(output)), nil
+	}
+
+	// Git before 2.36 has no -z; its output terminates fields with newlines
+	cmd = exec.Command("git", "worktree", "list", "--porcelain")
+	output, fallbackErr := cmd.Output()
+	if fallbackErr != nil {
 		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
+	return parseWorktreeList(newlineToNUL(string(output))), nil
+}
 
-	return parseWorktreeList(string(output)), nil
+// newlineToNUL converts the output of 'git worktree list --porcelain'
+// without -z into the NUL-terminated form parseWorktreeList reads
+func newlineToNUL(output string) string {
+	return strings.ReplaceAll(output, "\n", "\x00")
 }
 
+// parseWorktreeList parses the output of 'git worktree list --porcelain -z'.
+// Each attribute is terminated by NUL and records are separated by an empty attribute.
 func parseWorktreeList(output string) []Worktree {
 	var worktrees []Worktree
-	lines := strings.Split(strings.TrimSpace(output), "\n")
 
 	var current *Worktree
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	for _, field := range strings.Split(output, "\x00") {
+		if field == "" {
 			if current != nil {
 				worktrees = append(worktrees, *current)
 				current = nil
@@ -86,26 +152,34 @@ func parseWorktreeList(output string) []Worktree {
 			continue
 		}
 
-		if strings.HasPrefix(line, "worktree ") {
+		key, value, _ := strings.Cut(field, " ")
+		if key == "worktree" {
 			if current != nil {
 				worktrees = append(worktrees, *current)
 			}
-			current = &Worktree{
-				Path: strings.TrimPrefix(line, "worktree "),
-			}
-		} else if strings.HasPrefix(line, "branch ") {
-			if current != nil {
-				branchRef := strings.TrimPrefix(line, "branch ")
-				// Extract branch name from refs/heads/branch-name
-				parts := strings.Split(branchRef, "/")
-				if len(parts) > 0 {
-					current.Branch = parts[len(parts)-1]
-				}
-			}
-		} else if strings.HasPrefix(line, "bare") {
-			if current != nil {
-				current.IsMain = true
-			}
+			current = &Worktree{Path: value}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "HEAD":
+			current.Head = value
+		case "branch":
+			current.Branch = strings.TrimPrefix(value, "refs/heads/")
+		case "detached":
+			current.Detached = true
+		case "bare":
+			current.Bare = true
+			current.IsMain = true
+		case "locked":
+			current.Locked = true
+			current.LockedReason = value
+		case "prunable":
+			current.Prunable = true
+			current.PrunableReason = value
 		}
 	}
 
@@ -155,6 +229,19 @@ func IsWorktree(dir string) (bool, error) {
 	return strings.Contains(gitDir, "worktrees"), nil
 }
 
+// GetGitDir returns the absolute path of the private git directory of a worktree
+// (.git for the main worktree, .git/worktrees/<name> for linked worktrees)
+func GetGitDir(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--absolute-git-dir")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git dir: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
 // GetWorktreeMainPath returns the main worktree path for a given worktree
 func GetWorktreeMainPath(worktreeDir string) (string, error) {
 	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
@@ -165,6 +252,14 @@ func GetWorktreeMainPath(worktreeDir string) (string, error) {
 	}
 
 	gitCommonDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitCommonDir) {
+		// Older git versions print the common dir relative to worktreeDir
+		absDir, err := filepath.Abs(worktreeDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		gitCommonDir = filepath.Join(absDir, gitCommonDir)
+	}
 
 	// The common dir points to the main .git directory
 	// Get the parent of .git to get the main worktree path
@@ -179,3 +274,199 @@ func BranchExists(branchName string) bool {
 	err := cmd.Run()
 	return err == nil
 }
+
+// FindWorktree returns the worktree checked out at the given branch or located at the given path
+func FindWorktree(target string) (*Worktree, error) {
+	worktrees, err := ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, wt := range worktrees {
+		if wt.Branch == target {
+			return &wt, nil
+		}
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	absTarget = resolvePath(absTarget)
+
+	for _, wt := range worktrees {
+		if resolvePath(wt.Path) == absTarget {
+			return &wt, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no worktree found for branch or path: %s", target)
+}
+
+// resolvePath resolves symlinks in path, returning path unchanged on failure
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// UncommittedChanges returns the paths with staged, unstaged or untracked changes in a worktree
+func UncommittedChanges(dir string) ([]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "-z")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+
+	var paths []string
+	entries := strings.Split(string(output), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		paths = append(paths, strings.TrimSuffix(entry[3:], "/"))
+
+		// Renames and copies are followed by the original path
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++
+		}
+	}
+
+	return paths, nil
+}
+
+// RemoveWorktreeArgs returns the git arguments of RemoveWorktree
+func RemoveWorktreeArgs(path string, force bool) []string {
+	args := []string{"worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+	return append(args, path)
+}
+
+// RemoveWorktree removes a worktree. With force, uncommitted changes are discarded.
+func RemoveWorktree(path string, force bool) error {
+	cmd := exec.Command("git", RemoveWorktreeArgs(path, force)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove worktree: %w\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// PruneWorktreesArgs returns the git arguments of PruneWorktrees
+func PruneWorktreesArgs() []string {
+	return []string{"worktree", "prune"}
+}
+
+// PruneWorktrees removes administrative data of worktrees that no longer exist
+func PruneWorktrees() error {
+	cmd := exec.Command("git", PruneWorktreesArgs()...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// DeleteBranchArgs returns the git arguments of DeleteBranch
+func DeleteBranchArgs(branchName string, force bool) []string {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	return []string{"branch", flag, branchName}
+}
+
+// DeleteBranch deletes a local branch. With force, unmerged branches are deleted too.
+func DeleteBranch(branchName string, force bool) error {
+	cmd := exec.Command("git", DeleteBranchArgs(branchName, force)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to delete branch: %w\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// ShowFile returns the content of path at rev in the repository at dir
+func ShowFile(dir, rev, path string) ([]byte, error) {
+	cmd := exec.Command("git", "show", rev+":"+filepath.ToSlash(path))
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at %s: %w", path, rev, err)
+	}
+
+	return output, nil
+}
+
+// LocalBranchExists checks if a local branch exists
+func LocalBranchExists(branchName string) bool {
+	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branchName)
+	return cmd.Run() == nil
+}
+
+// FindRemoteBranch finds the remote-tracking branch for name. name may be a
+// remote branch itself ("origin/feature-x") or a branch name present on a
+// remote ("feature-x"). When several remotes have the branch, origin is
+// preferred. It returns the remote branch and the matching local branch name,
+// or empty strings if there is no match.
+func FindRemoteBranch(name string) (remoteBranch, localName string, err error) {
+	// name is a remote branch, e.g. origin/feature-x
+	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/remotes/"+name)
+	if cmd.Run() == nil {
+		if remote, branch, ok := strings.Cut(name, "/"); ok && remoteExists(remote) {
+			return name, branch, nil
+		}
+	}
+
+	cmd = exec.Command("git", "for-each-ref", "--format=%(refname)", "refs/remotes/*/"+name)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list remote branches: %w", err)
+	}
+
+	var matches []string
+	for _, ref := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		ref = strings.TrimPrefix(ref, "refs/remotes/")
+		// The pattern's * may span slashes, so make sure the remote part is a single segment
+		if ref == "" || strings.Count(ref, "/") != strings.Count(name, "/")+1 {
+			continue
+		}
+		matches = append(matches, ref)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", "", nil
+	case 1:
+		return matches[0], name, nil
+	}
+
+	for _, match := range matches {
+		if match == "origin/"+name {
+			return match, name, nil
+		}
+	}
+	return "", "", fmt.Errorf("branch '%s' exists on several remotes: %s", name, strings.Join(matches, ", "))
+}
+
+func remoteExists(remote string) bool {
+	cmd := exec.Command("git", "remote")
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	for _, r := range strings.Fields(string(output)) {
+		if r == remote {
+			return true
+		}
+	}
+	return false
+}
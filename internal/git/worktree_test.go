@@ -0,0 +1,169 @@
+package git
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseWorktreeList(t *testing.T) {
+	records := []string{
+		"worktree /repo\x00HEAD 1111111111111111111111111111111111111111\x00branch refs/heads/main\x00",
+		"worktree /repo/../feature auth\x00HEAD 2222222222222222222222222222222222222222\x00branch refs/heads/feature/auth\x00locked on usb drive\x00",
+		"worktree /tmp/line\nbreak\x00HEAD 3333333333333333333333333333333333333333\x00detached\x00prunable gitdir file points to non-existent location\x00",
+		"worktree /repo/locked\x00HEAD 4444444444444444444444444444444444444444\x00branch refs/heads/locked\x00locked\x00",
+	}
+	output := strings.Join(records, "\x00") + "\x00"
+
+	worktrees := parseWorktreeList(output)
+	if len(worktrees) != 4 {
+		t.Fatalf("expected 4 worktrees, got %d", len(worktrees))
+	}
+
+	main := worktrees[0]
+	if !main.IsMain || main.Branch != "main" || main.Path != "/repo" {
+		t.Errorf("unexpected main worktree: %+v", main)
+	}
+	if main.Head != "1111111111111111111111111111111111111111" {
+		t.Errorf("unexpected HEAD: %s", main.Head)
+	}
+
+	feature := worktrees[1]
+	if feature.IsMain {
+		t.Error("expected only the first worktree to be main")
+	}
+	if feature.Branch != "feature/auth" {
+		t.Errorf("expected full branch name feature/auth, got %s", feature.Branch)
+	}
+	if feature.Path != "/repo/../feature auth" {
+		t.Errorf("unexpected path: %q", feature.Path)
+	}
+	if !feature.Locked || feature.LockedReason != "on usb drive" {
+		t.Errorf("expected locked with reason, got %+v", feature)
+	}
+
+	detached := worktrees[2]
+	if detached.Path != "/tmp/line\nbreak" {
+		t.Errorf("expected path with newline, got %q", detached.Path)
+	}
+	if !detached.Detached || detached.Branch != "" {
+		t.Errorf("expected detached worktree, got %+v", detached)
+	}
+	if !detached.Prunable || detached.PrunableReason != "gitdir file points to non-existent location" {
+		t.Errorf("expected prunable with reason, got %+v", detached)
+	}
+
+	locked := worktrees[3]
+	if !locked.Locked || locked.LockedReason != "" {
+		t.Errorf("expected locked without reason, got %+v", locked)
+	}
+}
+
+func TestParseWorktreeListBare(t *testing.T) {
+	output := "worktree /repo.git\x00bare\x00\x00worktree /work\x00HEAD 1111111111111111111111111111111111111111\x00branch refs/heads/main\x00\x00"
+
+	worktrees := parseWorktreeList(output)
+	if len(worktrees) != 2 {
+		t.Fatalf("expected 2 worktrees, got %d", len(worktrees))
+	}
+	if !worktrees[0].Bare || !worktrees[0].IsMain {
+		t.Errorf("expected bare main worktree, got %+v", worktrees[0])
+	}
+	if worktrees[1].IsMain {
+		t.Error("expected linked worktree not to be main")
+	}
+}
+
+func TestParseWorktreeListWithoutZ(t *testing.T) {
+	// Output of git before 2.36, which has no -z
+	output := "worktree /repo\nHEAD 1111111111111111111111111111111111111111\nbranch refs/heads/main\n\nworktree /feature\nHEAD 2222222222222222222222222222222222222222\ndetached\nlocked\n\n"
+
+	worktrees := parseWorktreeList(newlineToNUL(output))
+	if len(worktrees) != 2 {
+		t.Fatalf("expected 2 worktrees, got %d", len(worktrees))
+	}
+	if !worktrees[0].IsMain || worktrees[0].Branch != "main" || worktrees[0].Path != "/repo" {
+		t.Errorf("unexpected main worktree: %+v", worktrees[0])
+	}
+	if worktrees[1].Path != "/feature" || !worktrees[1].Detached || !worktrees[1].Locked {
+		t.Errorf("unexpected linked worktree: %+v", worktrees[1])
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{CreateWorktreeArgs("feature", "/repo-feature", ""), "git worktree add -b feature /repo-feature"},
+		{CheckoutWorktreeArgs("feature", "/my repo/feature"), "git worktree add '/my repo/feature' feature"},
+		{DeleteBranchArgs("it's", true), `git branch -D 'it'\''s'`},
+		{[]string{"commit", "-m", ""}, "git commit -m ''"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatCommand(tt.args); got != tt.want {
+			t.Errorf("FormatCommand(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFindRemoteBranch(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=gws", "-c", "user.email=gws@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	git("init")
+	git("commit", "--allow-empty", "-m", "init")
+	for _, remote := range []string{"origin", "upstream", "fork"} {
+		git("remote", "add", remote, "https://example.com/"+remote+".git")
+	}
+	for _, ref := range []string{
+		"origin/feature-x",
+		"upstream/feature-x",
+		"upstream/only-upstream",
+		"upstream/team/auth",
+		"upstream/shared",
+		"fork/shared",
+	} {
+		git("update-ref", "refs/remotes/"+ref, "HEAD")
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		name         string
+		remoteBranch string
+		localName    string
+		wantErr      bool
+	}{
+		// origin wins when several remotes have the branch
+		{name: "feature-x", remoteBranch: "origin/feature-x", localName: "feature-x"},
+		{name: "only-upstream", remoteBranch: "upstream/only-upstream", localName: "only-upstream"},
+		// Branch names may contain slashes
+		{name: "team/auth", remoteBranch: "upstream/team/auth", localName: "team/auth"},
+		// A remote branch given with its remote
+		{name: "upstream/feature-x", remoteBranch: "upstream/feature-x", localName: "feature-x"},
+		{name: "upstream/team/auth", remoteBranch: "upstream/team/auth", localName: "team/auth"},
+		// Neither a prefix nor a suffix of upstream/team/auth matches
+		{name: "team"},
+		{name: "auth"},
+		{name: "missing"},
+		{name: "shared", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		remoteBranch, localName, err := FindRemoteBranch(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FindRemoteBranch(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if remoteBranch != tt.remoteBranch || localName != tt.localName {
+			t.Errorf("FindRemoteBranch(%q) = %q, %q, want %q, %q", tt.name, remoteBranch, localName, tt.remoteBranch, tt.localName)
+		}
+	}
+}
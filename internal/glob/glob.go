@@ -0,0 +1,221 @@
+package glob
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Match reports whether the slash-separated relative path name matches pattern.
+//
+// Patterns follow .gitignore conventions:
+//   - a pattern without a slash matches the base name at any depth ("*.log")
+//   - a pattern with a slash is anchored at the root ("tmp/*", "/build")
+//   - "**" matches zero or more path segments ("logs/**/*.txt")
+//   - a trailing slash is ignored ("tmp/" is the same as "tmp")
+func Match(pattern, name string) (bool, error) {
+	pattern = strings.TrimSuffix(pattern, "/")
+	name = strings.Trim(name, "/")
+
+	if !strings.Contains(pattern, "/") {
+		return matchSegment(pattern, path.Base(name))
+	}
+
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Validate checks that pattern is syntactically valid
+func Validate(pattern string) error {
+	pattern = strings.TrimPrefix(pattern, "!")
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("empty pattern")
+	}
+
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if segment == "**" {
+			continue
+		}
+		if strings.Contains(segment, "**") {
+			return fmt.Errorf("invalid pattern %q: ** must be a whole path segment", pattern)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// HasMeta reports whether pattern contains glob metacharacters
+func HasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// Expand returns the paths under root matching pattern, as slash-separated
+// paths relative to root in lexical order.
+//
+// Unlike Match, the pattern is always anchored at root: "*" matches only
+// top-level entries and "**/node_modules" matches node_modules at any depth.
+// Matched directories are not descended into, and .git directories are
+// skipped.
+func Expand(root, pattern string) ([]string, error) {
+	if err := Validate(pattern); err != nil {
+		return nil, err
+	}
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+
+	var matches []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories cannot contain matches we can sync
+			if p != root && os.IsPermission(err) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+
+		if matched, _ := matchSegments(segments, parts); matched {
+			matches = append(matches, strings.Join(parts, "/"))
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() && !matchPrefix(segments, parts) {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand pattern %q: %w", pattern, err)
+	}
+
+	return matches, nil
+}
+
+// matchPrefix reports whether a path below the directory name could match pattern
+func matchPrefix(pattern, name []string) bool {
+	for len(name) > 0 {
+		if len(pattern) == 0 {
+			return false
+		}
+		if pattern[0] == "**" {
+			return true
+		}
+		if matched, _ := matchSegment(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(pattern) > 0
+}
+
+func matchSegment(pattern, name string) (bool, error) {
+	matched, err := path.Match(pattern, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return matched, nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive ** segments
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				matched, err := matchSegments(pattern, name[i:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		matched, err := matchSegment(pattern[0], name[0])
+		if err != nil || !matched {
+			return false, err
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// Matcher evaluates an ordered list of patterns.
+// Patterns prefixed with "!" negate a previous match; the last matching
+// pattern decides the result.
+type Matcher struct {
+	patterns []matcherPattern
+}
+
+type matcherPattern struct {
+	pattern string
+	negate  bool
+}
+
+// NewMatcher creates a matcher from the given patterns
+func NewMatcher(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range patterns {
+		if err := Validate(p); err != nil {
+			return nil, err
+		}
+
+		mp := matcherPattern{pattern: p}
+		if strings.HasPrefix(p, "!") {
+			mp.negate = true
+			mp.pattern = strings.TrimPrefix(p, "!")
+		}
+		m.patterns = append(m.patterns, mp)
+	}
+	return m, nil
+}
+
+// Match reports whether name is matched by the pattern list
+func (m *Matcher) Match(name string) bool {
+	if m == nil {
+		return false
+	}
+
+	matched := false
+	for _, p := range m.patterns {
+		// Patterns are validated in NewMatcher, so errors cannot occur here
+		if ok, _ := Match(p.pattern, name); ok {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
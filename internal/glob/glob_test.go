@@ -0,0 +1,136 @@
+package glob
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.log", "debug.log", true},
+		{"*.log", "config/logs/debug.log", true},
+		{"*.log", "config/debug.txt", false},
+		{"tmp/*", "tmp/cache", true},
+		{"tmp/*", "config/tmp/cache", false},
+		{"tmp/*", "tmp", false},
+		{"/build", "build", true},
+		{"tmp/", "tmp", true},
+		{"**/cache", "cache", true},
+		{"**/cache", "a/b/cache", true},
+		{"config/**/*.log", "config/debug.log", true},
+		{"config/**/*.log", "config/a/b/debug.log", true},
+		{"config/**/*.log", "other/a/debug.log", false},
+		{"config/**", "config/a/b", true},
+		{"packages/*/node_modules", "packages/app/node_modules", true},
+		{"packages/*/node_modules", "packages/app/lib/node_modules", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			result, err := Match(tt.pattern, tt.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"*.log", "tmp/*", "**/node_modules", "!keep.log", "[a-z]*"}
+	for _, p := range valid {
+		if err := Validate(p); err != nil {
+			t.Errorf("expected %q to be valid: %v", p, err)
+		}
+	}
+
+	invalid := []string{"", "!", "[a-", "foo**bar/baz"}
+	for _, p := range invalid {
+		if err := Validate(p); err == nil {
+			t.Errorf("expected %q to be invalid", p)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m, err := NewMatcher([]string{"*.log", "!keep.log", "tmp/*"})
+	if err != nil {
+		t.Fatalf("failed to create matcher: %v", err)
+	}
+
+	tests := map[string]bool{
+		"debug.log":        true,
+		"config/debug.log": true,
+		"keep.log":         false,
+		"config/keep.log":  false,
+		"tmp/cache":        true,
+		"config/app.yml":   false,
+		"config/tmp/cache": false,
+	}
+
+	for name, expected := range tests {
+		if result := m.Match(name); result != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, result)
+		}
+	}
+
+	if _, err := NewMatcher([]string{"[a-"}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		".git/node_modules",
+		"node_modules/dep/node_modules",
+		"packages/a/node_modules",
+		"packages/b/node_modules",
+		"packages/c/src",
+		"apps/web/.next",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	for _, file := range []string{".env", ".env.local", "packages/a/.env"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"**/node_modules", []string{"node_modules", "packages/a/node_modules", "packages/b/node_modules"}},
+		{"packages/*/node_modules", []string{"packages/a/node_modules", "packages/b/node_modules"}},
+		{".env*", []string{".env", ".env.local"}},
+		{"**/.env", []string{".env", "packages/a/.env"}},
+		{"apps/*/.next/", []string{"apps/web/.next"}},
+		{"**/dist", nil},
+	}
+
+	for _, tt := range tests {
+		matches, err := Expand(root, tt.pattern)
+		if err != nil {
+			t.Errorf("Expand(%q) returned error: %v", tt.pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(matches, tt.expected) {
+			t.Errorf("Expand(%q) = %v, expected %v", tt.pattern, matches, tt.expected)
+		}
+	}
+
+	if _, err := Expand(root, "a**"); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
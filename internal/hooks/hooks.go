@@ -0,0 +1,112 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+// Hook names recognized in the hooks section of .gwt.yml
+const (
+	PreCreate  = "pre_create"
+	PostCreate = "post_create"
+	PreSync    = "pre_sync"
+	PostSync   = "post_sync"
+	PreRemove  = "pre_remove"
+	// Install is the hook name passed to resource install commands
+	Install = "install"
+)
+
+// Env describes the worktree a hook is run for
+type Env struct {
+	Branch       string
+	WorktreePath string
+	MainPath     string
+}
+
+// IsPre reports whether the hook runs before an operation and can abort it
+func IsPre(name string) bool {
+	return strings.HasPrefix(name, "pre_")
+}
+
+// aborts reports whether a failure of the hook fails the command. Besides
+// pre_* hooks, post_create does: it is part of setting up the worktree,
+// which gws create rolls back when it fails.
+func aborts(name string) bool {
+	return IsPre(name) || name == PostCreate
+}
+
+// Command returns the command of the named hook and whether it is defined
+func Command(cfg *config.Config, name string) (string, bool) {
+	command, ok := cfg.Hooks[name]
+	if !ok || strings.TrimSpace(command) == "" {
+		return "", false
+	}
+	return command, true
+}
+
+// Run executes the named hook if it is defined in the config.
+// The hook runs in the worktree (or the main worktree if the worktree does
+// not exist yet) with its output streamed to the terminal. Failures of hooks
+// that run after their operation, such as post_sync, are only reported as
+// warnings.
+func Run(cfg *config.Config, name string, env Env) error {
+	command, ok := Command(cfg, name)
+	if !ok {
+		return nil
+	}
+
+	fmt.Printf("▶ Running %s hook: %s\n", name, command)
+
+	if err := run(name, command, env); err != nil {
+		if aborts(name) {
+			return fmt.Errorf("%s hook failed: %w", name, err)
+		}
+		fmt.Printf("⚠️  %s hook failed: %v\n", name, err)
+	}
+
+	return nil
+}
+
+// RunInstall executes the install command of a resource in the worktree.
+// GWS_RESOURCE is set to the resource path.
+func RunInstall(resource, command string, env Env) error {
+	fmt.Printf("▶ Running install for %s: %s\n", resource, command)
+
+	if err := run(Install, command, env, "GWS_RESOURCE="+resource); err != nil {
+		return fmt.Errorf("install for %s failed: %w", resource, err)
+	}
+
+	return nil
+}
+
+func run(name, command string, env Env, extraEnv ...string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	cmd.Dir = env.MainPath
+	if info, err := os.Stat(env.WorktreePath); err == nil && info.IsDir() {
+		cmd.Dir = env.WorktreePath
+	}
+
+	cmd.Env = append(os.Environ(),
+		"GWS_HOOK="+name,
+		"GWS_BRANCH="+env.Branch,
+		"GWS_WORKTREE_PATH="+env.WorktreePath,
+		"GWS_MAIN_PATH="+env.MainPath,
+	)
+	cmd.Env = append(cmd.Env, extraEnv...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
@@ -0,0 +1,72 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test require sh")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gws-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := &config.Config{
+		Hooks: map[string]string{
+			PostCreate: `echo "$GWS_HOOK $GWS_BRANCH $GWS_MAIN_PATH" > hook.out`,
+			PreSync:    "exit 3",
+			PostSync:   "exit 4",
+		},
+	}
+	env := Env{
+		Branch:       "feature/auth",
+		WorktreePath: tmpDir,
+		MainPath:     "/main",
+	}
+
+	if err := Run(cfg, PostCreate, env); err != nil {
+		t.Fatalf("expected hook to succeed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "hook.out"))
+	if err != nil {
+		t.Fatalf("hook did not run in worktree: %v", err)
+	}
+	expected := "post_create feature/auth /main"
+	if strings.TrimSpace(string(data)) != expected {
+		t.Errorf("expected %q, got %q", expected, strings.TrimSpace(string(data)))
+	}
+
+	if err := Run(cfg, PreSync, env); err == nil {
+		t.Error("expected error for non-zero exit")
+	}
+	if err := Run(cfg, PostSync, env); err != nil {
+		t.Errorf("expected post_sync failure to be a warning: %v", err)
+	}
+
+	if err := Run(cfg, PreRemove, env); err != nil {
+		t.Errorf("expected undefined hook to be a no-op: %v", err)
+	}
+}
+
+func TestIsPre(t *testing.T) {
+	if !IsPre(PreCreate) || !IsPre(PreSync) || !IsPre(PreRemove) {
+		t.Error("expected pre_* hooks to be recognized")
+	}
+	if IsPre(PostCreate) || IsPre(PostSync) {
+		t.Error("expected post_* hooks not to be pre hooks")
+	}
+	if !aborts(PostCreate) || aborts(PostSync) {
+		t.Error("expected only post_create of the post_* hooks to abort")
+	}
+}
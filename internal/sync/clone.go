@@ -0,0 +1,142 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+// Strategies reported in SyncResult.Strategy, in the order they are tried
+const (
+	StrategyReflink       = "reflink"
+	StrategyHardlink      = "hardlink"
+	StrategyCopyFileRange = "copy_file_range"
+	StrategyCopy          = "copy"
+)
+
+var strategyOrder = []string{StrategyReflink, StrategyHardlink, StrategyCopyFileRange, StrategyCopy}
+
+// errCloneUnsupported is returned by reflink and copyFileRange when the
+// platform or filesystem cannot copy the file that way
+var errCloneUnsupported = errors.New("not supported by the filesystem")
+
+// copyFile copies a single file using the copier's strategy
+func (c *copier) copyFile(source, dest string) error {
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	if c.strategy == config.CopyStrategyHardlink {
+		if err := os.Link(source, dest); err != nil {
+			return fmt.Errorf("failed to hardlink file: %w", err)
+		}
+		c.use(StrategyHardlink)
+		return nil
+	}
+
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer destFile.Close()
+
+	strategy, err := c.copyContent(destFile, sourceFile, sourceInfo.Size())
+	if err != nil {
+		return err
+	}
+	c.use(strategy)
+
+	return copyMetadata(source, dest, sourceInfo)
+}
+
+// copyContent copies the content of source to dest and returns the strategy
+// that was used. With the auto strategy, reflinks and copy_file_range are
+// tried first; once the filesystem rejects one, it is not tried again for
+// the rest of the resource.
+func (c *copier) copyContent(dest, source *os.File, size int64) (string, error) {
+	switch c.strategy {
+	case config.CopyStrategyCopy:
+		return StrategyCopy, streamCopy(dest, source)
+	case config.CopyStrategyReflink:
+		if err := reflink(dest, source); err != nil {
+			return "", fmt.Errorf("failed to clone file: %w", err)
+		}
+		return StrategyReflink, nil
+	}
+
+	c.mu.Lock()
+	tryReflink, tryCopyFileRange := !c.noReflink, !c.noCopyFileRange
+	c.mu.Unlock()
+
+	if tryReflink {
+		err := reflink(dest, source)
+		if err == nil {
+			return StrategyReflink, nil
+		}
+		if !errors.Is(err, errCloneUnsupported) {
+			return "", fmt.Errorf("failed to clone file: %w", err)
+		}
+		c.mu.Lock()
+		c.noReflink = true
+		c.mu.Unlock()
+	}
+
+	if tryCopyFileRange {
+		err := copyFileRange(dest, source, size)
+		if err == nil {
+			return StrategyCopyFileRange, nil
+		}
+		if !errors.Is(err, errCloneUnsupported) {
+			return "", fmt.Errorf("failed to copy file: %w", err)
+		}
+		c.mu.Lock()
+		c.noCopyFileRange = true
+		c.mu.Unlock()
+	}
+
+	return StrategyCopy, streamCopy(dest, source)
+}
+
+// streamCopy copies the content of source to dest through user space
+func streamCopy(dest, source *os.File) error {
+	// Hide the source's type so that io.Copy does not hand the copy to the
+	// kernel, which may share blocks on some filesystems
+	if _, err := io.Copy(dest, struct{ io.Reader }{source}); err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return nil
+}
+
+// use records that a strategy was used for a file
+func (c *copier) use(strategy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.used == nil {
+		c.used = make(map[string]bool)
+	}
+	c.used[strategy] = true
+}
+
+// strategies returns the strategies used so far, comma separated
+func (c *copier) strategies() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var used []string
+	for _, strategy := range strategyOrder {
+		if c.used[strategy] {
+			used = append(used, strategy)
+		}
+	}
+	return strings.Join(used, ", ")
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// ficlone returns the FICLONE ioctl request, _IOW(0x94, 9, int), whose
+// direction bits differ on mips and powerpc
+func ficlone() uintptr {
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		return 0x80049409
+	}
+	return 0x40049409
+}
+
+// sysCopyFileRange is the copy_file_range syscall number, which the syscall
+// package does not define for most architectures
+var sysCopyFileRange = map[string]uintptr{
+	"386":      377,
+	"amd64":    326,
+	"arm":      391,
+	"arm64":    285,
+	"loong64":  285,
+	"mips":     4360,
+	"mipsle":   4360,
+	"mips64":   5320,
+	"mips64le": 5320,
+	"ppc64":    379,
+	"ppc64le":  379,
+	"riscv64":  285,
+	"s390x":    375,
+}[runtime.GOARCH]
+
+// reflink makes dest share the blocks of source (Btrfs, XFS and others)
+func reflink(dest, source *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dest.Fd(), ficlone(), source.Fd())
+	if errno != 0 {
+		return cloneError(errno)
+	}
+	return nil
+}
+
+// copyFileRange copies size bytes from source to dest in the kernel. The
+// file offsets are left untouched so that a failed copy can be redone.
+func copyFileRange(dest, source *os.File, size int64) error {
+	if sysCopyFileRange == 0 {
+		return errCloneUnsupported
+	}
+
+	var sourceOffset, destOffset int64
+	for sourceOffset < size {
+		n, _, errno := syscall.Syscall6(sysCopyFileRange,
+			source.Fd(), uintptr(unsafe.Pointer(&sourceOffset)),
+			dest.Fd(), uintptr(unsafe.Pointer(&destOffset)),
+			uintptr(min(size-sourceOffset, 1<<30)), 0)
+		if errno != 0 {
+			return cloneError(errno)
+		}
+		if n == 0 {
+			// Files whose size is not known up front, e.g. in /proc
+			if sourceOffset == 0 {
+				return errCloneUnsupported
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// cloneError marks the errors returned when the filesystem or kernel does
+// not support the operation for these files
+func cloneError(errno syscall.Errno) error {
+	switch errno {
+	case syscall.EXDEV, syscall.EOPNOTSUPP, syscall.EINVAL, syscall.ENOTTY, syscall.ENOSYS, syscall.EPERM:
+		return fmt.Errorf("%w (%v)", errCloneUnsupported, errno)
+	}
+	return errno
+}
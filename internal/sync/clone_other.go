@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sync
+
+import "os"
+
+func reflink(dest, source *os.File) error {
+	return errCloneUnsupported
+}
+
+func copyFileRange(dest, source *os.File, size int64) error {
+	return errCloneUnsupported
+}
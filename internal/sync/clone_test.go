@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestSyncResourcesCopyStrategy(t *testing.T) {
+	files := []string{"target/debug/app", "target/debug/deps/lib.rlib", ".env"}
+
+	syncWith := func(t *testing.T, strategy string) (SyncResult, string, string) {
+		t.Helper()
+		sourceDir, destDir := t.TempDir(), t.TempDir()
+		writeFiles(t, sourceDir, files)
+		cfg := &config.Config{
+			Resources: config.Resources{Copy: []string{"target"}},
+			ResourceOptions: map[string]config.ResourceOptions{
+				"target": {CopyStrategy: strategy},
+			},
+		}
+
+		results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+		if err != nil {
+			t.Fatalf("failed to sync: %v", err)
+		}
+		return results[0], sourceDir, destDir
+	}
+	assertCopied := func(t *testing.T, sourceDir, destDir string) {
+		t.Helper()
+		for _, file := range files[:2] {
+			want, _ := os.ReadFile(filepath.Join(sourceDir, file))
+			got, err := os.ReadFile(filepath.Join(destDir, file))
+			if err != nil || string(got) != string(want) {
+				t.Errorf("expected %s to be copied, got %q, %v", file, got, err)
+			}
+		}
+	}
+
+	t.Run("copy", func(t *testing.T) {
+		result, sourceDir, destDir := syncWith(t, config.CopyStrategyCopy)
+		if !result.Success || result.Strategy != StrategyCopy {
+			t.Fatalf("expected a plain copy, got %+v", result)
+		}
+		assertCopied(t, sourceDir, destDir)
+	})
+
+	t.Run("hardlink", func(t *testing.T) {
+		result, sourceDir, destDir := syncWith(t, config.CopyStrategyHardlink)
+		if !result.Success || result.Strategy != StrategyHardlink {
+			t.Fatalf("expected hardlinks, got %+v", result)
+		}
+		sourceInfo, _ := os.Stat(filepath.Join(sourceDir, "target/debug/app"))
+		destInfo, _ := os.Stat(filepath.Join(destDir, "target/debug/app"))
+		if !os.SameFile(sourceInfo, destInfo) {
+			t.Error("expected file to be hardlinked")
+		}
+	})
+
+	t.Run("auto", func(t *testing.T) {
+		result, sourceDir, destDir := syncWith(t, "")
+		if !result.Success || result.Strategy == "" || result.Strategy == StrategyHardlink {
+			t.Fatalf("expected files to be cloned or copied, got %+v", result)
+		}
+		assertCopied(t, sourceDir, destDir)
+	})
+
+	t.Run("reflink", func(t *testing.T) {
+		result, sourceDir, destDir := syncWith(t, config.CopyStrategyReflink)
+		if !result.Success {
+			if errors.Is(result.Error, errCloneUnsupported) {
+				t.Skipf("reflinks are not supported here: %v", result.Error)
+			}
+			t.Fatalf("failed to reflink: %v", result.Error)
+		}
+		if result.Strategy != StrategyReflink {
+			t.Errorf("expected reflinks, got %+v", result)
+		}
+		assertCopied(t, sourceDir, destDir)
+	})
+}
@@ -0,0 +1,121 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+// changedLockfiles returns the lockfiles that differ between sourceDir and
+// destDir, reading those of destDir from rev when it is set
+func changedLockfiles(sourceDir, destDir, rev string, lockfiles []string) []string {
+	var changed []string
+	for _, lockfile := range lockfiles {
+		if lockfileDiverged(sourceDir, destDir, rev, lockfile) {
+			changed = append(changed, lockfile)
+		}
+	}
+	return changed
+}
+
+// lockfileReason describes changed lockfiles
+func lockfileReason(changed []string) string {
+	if len(changed) == 1 {
+		return changed[0] + " differs from the main worktree"
+	}
+	return strings.Join(changed, ", ") + " differ from the main worktree"
+}
+
+// planResource decides how a resource is synced, taking the lockfiles
+// declared in its resource options into account
+func (s *syncer) planResource(resource string, mode SyncMode) ResourcePlan {
+	opts, ok := s.resources.options(s.cfg, resource)
+	if !ok || len(opts.Lockfiles) == 0 {
+		return s.planSync(resource, mode, false)
+	}
+
+	changed := changedLockfiles(s.sourceDir, s.destDir, s.opts.Rev, opts.Lockfiles)
+	if len(changed) == 0 {
+		return s.planSync(resource, mode, false)
+	}
+	reason := lockfileReason(changed)
+
+	switch opts.OnLockfileChange {
+	case config.LockfileCopy:
+		plan := s.planSync(resource, SyncModeCopy, true)
+		plan.Warning = reason
+		if plan.creates() {
+			plan.Install = opts.Install
+		}
+		return plan
+
+	case config.LockfileHook:
+		destPath := filepath.Join(s.destDir, resource)
+		plan := ResourcePlan{Resource: resource, Action: ActionHold, Reason: reason}
+		plan.Unlink = isLinkTo(destPath, filepath.Join(s.sourceDir, resource))
+		if !plan.Unlink && exists(destPath) {
+			plan.Reason += "; keeping the worktree's own copy"
+		} else {
+			plan.Install = opts.Install
+		}
+		return plan
+
+	default:
+		plan := s.planSync(resource, mode, false)
+		plan.Warning = reason
+		return plan
+	}
+}
+
+// unlinkFromSource removes a destination that is a symlink to the source,
+// so that the resource can be replaced by a copy of its own
+func (s *syncer) unlinkFromSource(resource string) error {
+	destPath := filepath.Join(s.destDir, resource)
+	sourcePath := filepath.Join(s.sourceDir, resource)
+	if !isLinkTo(destPath, sourcePath) {
+		return nil
+	}
+
+	if err := os.Remove(destPath); err != nil {
+		return fmt.Errorf("failed to unlink %s: %w", resource, err)
+	}
+	s.tx.record(resource, "Relinked "+resource, func() error {
+		return createSymlink(sourcePath, destPath)
+	})
+	s.mu.Lock()
+	delete(s.manifest.Resources, resource)
+	s.mu.Unlock()
+	return nil
+}
+
+// checkLockfiles adjusts the status of a resource whose lockfiles differ
+// from the main worktree
+func checkLockfiles(status *ResourceStatus, opts config.ResourceOptions, sourceDir, destDir string) {
+	changed := changedLockfiles(sourceDir, destDir, "", opts.Lockfiles)
+	if len(changed) == 0 || status.State == StateMissing {
+		return
+	}
+	reason := lockfileReason(changed)
+
+	if opts.OnLockfileChange == config.LockfileCopy || opts.OnLockfileChange == config.LockfileHook {
+		// The worktree is expected to have its own copy
+		if status.Kind == KindSymlink && status.PointsToMain {
+			status.State = StateStale
+			status.Detail = reason + "; run gws sync to replace the link"
+			return
+		}
+		if status.Kind != KindSymlink {
+			status.State = StateSynced
+			status.Detail = "own copy for the worktree's " + strings.Join(changed, ", ")
+		}
+		return
+	}
+
+	if status.State == StateSynced {
+		status.State = StateStale
+		status.Detail = reason
+	}
+}
@@ -0,0 +1,120 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestSyncResourcesLockfiles(t *testing.T) {
+	newConfig := func(action, install string) *config.Config {
+		return &config.Config{
+			Resources: config.Resources{Symlink: []string{"vendor"}},
+			ResourceOptions: map[string]config.ResourceOptions{
+				"vendor": {Lockfiles: []string{"Gemfile.lock"}, OnLockfileChange: action, Install: install},
+			},
+		}
+	}
+	setup := func(t *testing.T, diverged bool) (string, string) {
+		sourceDir, destDir := t.TempDir(), t.TempDir()
+		writeFiles(t, sourceDir, []string{"Gemfile.lock", "vendor/bundle/gem.rb"})
+		writeFiles(t, destDir, []string{"Gemfile.lock"})
+		if diverged {
+			if err := os.WriteFile(filepath.Join(destDir, "Gemfile.lock"), []byte("changed"), 0644); err != nil {
+				t.Fatalf("failed to write lockfile: %v", err)
+			}
+		}
+		return sourceDir, destDir
+	}
+	syncOne := func(t *testing.T, cfg *config.Config, sourceDir, destDir string) SyncResult {
+		t.Helper()
+		results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+		if err != nil {
+			t.Fatalf("failed to sync: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("expected one result, got %+v", results)
+		}
+		return results[0]
+	}
+	state := func(t *testing.T, cfg *config.Config, sourceDir, destDir string) ResourceStatus {
+		t.Helper()
+		report, err := CheckSyncStatus(cfg, sourceDir, destDir, false)
+		if err != nil {
+			t.Fatalf("failed to check status: %v", err)
+		}
+		return report.Resources[0]
+	}
+
+	t.Run("matching lockfile", func(t *testing.T) {
+		sourceDir, destDir := setup(t, false)
+		cfg := newConfig("", "")
+
+		result := syncOne(t, cfg, sourceDir, destDir)
+		if result.Mode != "symlink" || result.Warning != "" {
+			t.Errorf("expected a plain link, got %+v", result)
+		}
+		if rs := state(t, cfg, sourceDir, destDir); rs.State != StateSynced {
+			t.Errorf("expected synced, got %+v", rs)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		sourceDir, destDir := setup(t, true)
+		cfg := newConfig(config.LockfileWarn, "")
+
+		result := syncOne(t, cfg, sourceDir, destDir)
+		if result.Mode != "symlink" || result.Warning != "Gemfile.lock differs from the main worktree" {
+			t.Errorf("expected a link with a warning, got %+v", result)
+		}
+		if rs := state(t, cfg, sourceDir, destDir); rs.State != StateStale || rs.Detail != "Gemfile.lock differs from the main worktree" {
+			t.Errorf("expected stale, got %+v", rs)
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		sourceDir, destDir := setup(t, false)
+		cfg := newConfig(config.LockfileCopy, "bundle install")
+
+		// Link while the lockfiles match, then change the worktree's lockfile
+		syncOne(t, cfg, sourceDir, destDir)
+		if err := os.WriteFile(filepath.Join(destDir, "Gemfile.lock"), []byte("changed"), 0644); err != nil {
+			t.Fatalf("failed to write lockfile: %v", err)
+		}
+		if rs := state(t, cfg, sourceDir, destDir); rs.State != StateStale {
+			t.Errorf("expected linked resource to be stale, got %+v", rs)
+		}
+
+		result := syncOne(t, cfg, sourceDir, destDir)
+		if result.Mode != "copy" || result.Install != "bundle install" {
+			t.Errorf("expected a copy with an install command, got %+v", result)
+		}
+		info, err := os.Lstat(filepath.Join(destDir, "vendor"))
+		if err != nil || !info.IsDir() {
+			t.Fatalf("expected vendor to be a directory, got %v", err)
+		}
+		if rs := state(t, cfg, sourceDir, destDir); rs.State != StateSynced {
+			t.Errorf("expected own copy to be synced, got %+v", rs)
+		}
+
+		// An existing copy is not installed again
+		if result := syncOne(t, cfg, sourceDir, destDir); result.Mode != "exists" || result.Install != "" {
+			t.Errorf("expected existing copy to be kept, got %+v", result)
+		}
+	})
+
+	t.Run("hook", func(t *testing.T) {
+		sourceDir, destDir := setup(t, true)
+		cfg := newConfig(config.LockfileHook, "bundle install")
+
+		result := syncOne(t, cfg, sourceDir, destDir)
+		if result.Mode != "held" || result.Install != "bundle install" {
+			t.Errorf("expected resource to be held with an install command, got %+v", result)
+		}
+		if _, err := os.Lstat(filepath.Join(destDir, "vendor")); err == nil {
+			t.Error("expected vendor not to be created")
+		}
+	})
+}
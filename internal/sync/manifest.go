@@ -0,0 +1,379 @@
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fs0414/git-worktree-sync/internal/git"
+	"github.com/fs0414/git-worktree-sync/internal/glob"
+)
+
+// ManifestFileName is the name of the sync manifest inside a worktree's git dir
+const ManifestFileName = "gws-manifest.json"
+
+// Version is the gws version recorded in manifests. It is set by the main package.
+var Version = "dev"
+
+// Manifest records what gws synced into a worktree
+type Manifest struct {
+	Resources map[string]ManifestEntry `json:"resources"`
+}
+
+// ManifestEntry describes a single synced resource at the time it was synced
+type ManifestEntry struct {
+	Resource string `json:"resource"`
+	Mode     string `json:"mode"`
+	Source   string `json:"source"`
+	// Size, ModTime and Hash describe the source. For directories Size is the
+	// total size of all files and ModTime the newest modification time. Hash
+	// covers the path, size and modification time of every file, not file
+	// contents, which are only compared when the hashes disagree.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Hash    string    `json:"hash,omitempty"`
+	// DestHash is the hash of the copied destination (copy mode only)
+	DestHash   string    `json:"dest_hash,omitempty"`
+	GwsVersion string    `json:"gws_version"`
+	SyncedAt   time.Time `json:"synced_at"`
+}
+
+// manifestPath returns the manifest location for the worktree at dir
+func manifestPath(dir string) (string, error) {
+	gitDir, err := git.GetGitDir(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, ManifestFileName), nil
+}
+
+// LoadManifest loads the manifest of the worktree at dir.
+// An empty manifest is returned if none has been written yet.
+func LoadManifest(dir string) (*Manifest, error) {
+	manifest := &Manifest{Resources: make(map[string]ManifestEntry)}
+
+	path, err := manifestPath(dir)
+	if err != nil {
+		return manifest, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Resources == nil {
+		manifest.Resources = make(map[string]ManifestEntry)
+	}
+
+	return manifest, nil
+}
+
+// Save writes the manifest into the git dir of the worktree at dir.
+// Directories that are not git worktrees have no manifest and are skipped.
+func (m *Manifest) Save(dir string) error {
+	path, err := manifestPath(dir)
+	if err != nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// snapshotManifest returns a function that restores the manifest of the
+// worktree at dir to its current state
+func snapshotManifest(dir string) (func() error, error) {
+	path, err := manifestPath(dir)
+	if err != nil {
+		return func() error { return nil }, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return func() error {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove manifest: %w", err)
+			}
+			return nil
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return func() error {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to restore manifest: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// newManifestEntry records the current state of a synced resource
+func newManifestEntry(resource, mode, sourcePath, destPath string) (ManifestEntry, error) {
+	entry := ManifestEntry{
+		Resource:   resource,
+		Mode:       mode,
+		Source:     sourcePath,
+		GwsVersion: Version,
+		SyncedAt:   time.Now().UTC(),
+	}
+
+	// Symlinks always reflect the source, so only record its top-level state
+	// instead of walking potentially huge trees such as node_modules
+	if mode != "copy" {
+		info, err := os.Stat(sourcePath)
+		if err != nil {
+			return entry, err
+		}
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime().UTC()
+		return entry, nil
+	}
+
+	fp, err := fingerprint(sourcePath)
+	if err != nil {
+		return entry, err
+	}
+	entry.Size = fp.size
+	entry.ModTime = fp.modTime
+	entry.Hash = fp.hash
+
+	destFp, err := fingerprint(destPath)
+	if err != nil {
+		return entry, err
+	}
+	entry.DestHash = destFp.hash
+
+	return entry, nil
+}
+
+type fileFingerprint struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// fingerprint summarizes a file or directory tree without following
+// symlinks or reading file contents. The hash covers the path, size and
+// modification time of every file, so that checking it stays cheap for
+// trees such as node_modules; sameContents confirms a mismatch.
+func fingerprint(path string) (fileFingerprint, error) {
+	var fp fileFingerprint
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fp, err
+	}
+
+	if !info.IsDir() {
+		fp.size = info.Size()
+		fp.modTime = info.ModTime().UTC()
+		h := sha256.New()
+		io.WriteString(h, fingerprintLine(path, ".", info)+"\n")
+		fp.hash = "sha256:" + hex.EncodeToString(h.Sum(nil))
+		return fp, nil
+	}
+
+	var lines []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(fp.modTime) {
+			fp.modTime = info.ModTime().UTC()
+		}
+		if info.Mode().IsRegular() {
+			fp.size += info.Size()
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fingerprintLine(p, filepath.ToSlash(rel), info))
+		return nil
+	})
+	if err != nil {
+		return fp, err
+	}
+
+	sort.Strings(lines)
+	h := sha256.New()
+	for _, line := range lines {
+		io.WriteString(h, line+"\n")
+	}
+	fp.hash = "sha256:" + hex.EncodeToString(h.Sum(nil))
+
+	return fp, nil
+}
+
+// fingerprintLine describes a single entry of a fingerprint
+func fingerprintLine(path, rel string, info os.FileInfo) string {
+	switch {
+	case info.IsDir():
+		return rel + "/"
+	case info.Mode()&os.ModeSymlink != 0:
+		target, _ := os.Readlink(path)
+		return rel + " -> " + target
+	case info.Mode().IsRegular():
+		return fmt.Sprintf("%s %d %d", rel, info.Size(), info.ModTime().UnixNano())
+	default:
+		return fmt.Sprintf("%s %s", rel, info.Mode().Type())
+	}
+}
+
+// sameContents reports whether the copy at destPath holds the same entries
+// and file contents as sourcePath, leaving out the paths exclude patterns
+// skip when copying. rel is the slash-separated path of the resource. Files
+// are only read when their sizes match but their modification times differ,
+// or for every file with verify.
+func sameContents(sourcePath, destPath, rel string, exclude *glob.Matcher, verify bool) (bool, error) {
+	// A resource that is itself a symlink is copied from its target
+	if resolved, err := filepath.EvalSymlinks(sourcePath); err == nil {
+		sourcePath = resolved
+	}
+
+	// Every copied entry must match its source
+	same := true
+	err := filepath.WalkDir(destPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(destPath, p)
+		if err != nil {
+			return err
+		}
+		destInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		sourceInfo, err := os.Lstat(filepath.Join(sourcePath, relPath))
+		if err != nil {
+			same = false
+			return filepath.SkipAll
+		}
+		if relPath == "." && destInfo.IsDir() && sourceInfo.IsDir() {
+			return nil
+		}
+		if !sameFile(filepath.Join(sourcePath, relPath), p, sourceInfo, destInfo, verify) {
+			same = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil || !same {
+		return false, err
+	}
+
+	// Every source entry that is not excluded must have been copied
+	err = filepath.WalkDir(sourcePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(sourcePath, p)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if exclude.Match(rel + "/" + filepath.ToSlash(relPath)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, err := os.Lstat(filepath.Join(destPath, relPath)); err != nil {
+			same = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return same, err
+}
+
+// sameFile reports whether two entries of the same path in a source and a
+// copy match, reading contents only when the metadata cannot tell
+func sameFile(sourcePath, destPath string, sourceInfo, destInfo os.FileInfo, verify bool) bool {
+	if sourceInfo.Mode().Type() != destInfo.Mode().Type() {
+		return false
+	}
+	switch {
+	case sourceInfo.IsDir():
+		return true
+	case sourceInfo.Mode()&os.ModeSymlink != 0:
+		sourceTarget, _ := os.Readlink(sourcePath)
+		destTarget, _ := os.Readlink(destPath)
+		return sourceTarget == destTarget
+	case !sourceInfo.Mode().IsRegular():
+		return true
+	}
+
+	if sourceInfo.Size() != destInfo.Size() {
+		return false
+	}
+	if !verify && sourceInfo.ModTime().Equal(destInfo.ModTime()) {
+		return true
+	}
+	sourceHash, err := hashFile(sourcePath, sourceInfo)
+	if err != nil {
+		return false
+	}
+	destHash, err := hashFile(destPath, destInfo)
+	return err == nil && sourceHash == destHash
+}
+
+// hashFile returns the content hash of a file, or of the target for a symlink
+func hashFile(path string, info os.FileInfo) (string, error) {
+	h := sha256.New()
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, target)
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contentHash returns the hash hashFile computes for a file holding data
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
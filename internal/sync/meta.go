@@ -0,0 +1,38 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// copyMetadata gives dest the permissions, extended attributes, timestamps
+// and, when running as root, the owner of the source described by info.
+// Extended attributes and owners are copied on a best-effort basis, as the
+// destination filesystem or the user may not support them.
+func copyMetadata(source, dest string, info os.FileInfo) error {
+	if uid, gid, ok := fileOwner(info); ok && os.Geteuid() == 0 {
+		// Changing the owner may clear the setuid and setgid bits, so it
+		// comes before the permissions
+		os.Lchown(dest, uid, gid)
+	}
+
+	// Attributes are set while dest is still writable
+	copyXattrs(source, dest)
+
+	if err := os.Chmod(dest, info.Mode()); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	if err := os.Chtimes(dest, fileAtime(info), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to set timestamps: %w", err)
+	}
+
+	return nil
+}
+
+// isLoop reports whether err was caused by a symlink loop
+func isLoop(err error) bool {
+	return errors.Is(err, syscall.ELOOP)
+}
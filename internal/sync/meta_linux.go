@@ -0,0 +1,57 @@
+//go:build linux
+
+package sync
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"time"
+)
+
+func fileAtime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atim.Unix())
+	}
+	return info.ModTime()
+}
+
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Uid), int(stat.Gid), true
+	}
+	return 0, 0, false
+}
+
+func mkfifo(path string, mode os.FileMode) error {
+	return syscall.Mkfifo(path, uint32(mode.Perm()))
+}
+
+// copyXattrs copies the extended attributes of source to dest, skipping
+// those that cannot be read or set
+func copyXattrs(source, dest string) {
+	size, err := syscall.Listxattr(source, nil)
+	if err != nil || size == 0 {
+		return
+	}
+	names := make([]byte, size)
+	if size, err = syscall.Listxattr(source, names); err != nil {
+		return
+	}
+
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+		size, err := syscall.Getxattr(source, attr, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, size)
+		if size, err = syscall.Getxattr(source, attr, value); err != nil {
+			continue
+		}
+		syscall.Setxattr(dest, attr, value[:size], 0)
+	}
+}
@@ -0,0 +1,37 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestSyncResourcesCopyXattrs(t *testing.T) {
+	sourceDir, destDir := t.TempDir(), t.TempDir()
+	writeFiles(t, sourceDir, []string{"data/db.sqlite"})
+	file := filepath.Join(sourceDir, "data/db.sqlite")
+	if err := syscall.Setxattr(file, "user.gws.test", []byte("kept"), 0); err != nil {
+		t.Skipf("extended attributes are not supported here: %v", err)
+	}
+	// A read-only file still receives its attributes
+	if err := os.Chmod(file, 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Resources: config.Resources{Copy: []string{"data"}}}
+	if _, err := SyncResources(cfg, sourceDir, destDir, Options{}); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	value := make([]byte, 16)
+	size, err := syscall.Getxattr(filepath.Join(destDir, "data/db.sqlite"), "user.gws.test", value)
+	if err != nil {
+		t.Fatalf("expected extended attribute to be copied: %v", err)
+	}
+	if string(value[:size]) != "kept" {
+		t.Errorf("expected extended attribute %q, got %q", "kept", value[:size])
+	}
+}
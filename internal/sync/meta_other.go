@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package sync
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+func fileAtime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+func mkfifo(path string, mode os.FileMode) error {
+	return errors.ErrUnsupported
+}
+
+func copyXattrs(source, dest string) {}
@@ -0,0 +1,125 @@
+//go:build linux || darwin
+
+package sync
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestSyncResourcesCopyFaithful(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := filepath.Join(root, "main")
+	destDir := filepath.Join(root, "feature")
+	writeFiles(t, sourceDir, []string{"node_modules/tool/cli.js", "node_modules/locked/index.js"})
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("failed to create worktree: %v", err)
+	}
+	modules := filepath.Join(sourceDir, "node_modules")
+
+	mustDo := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustDo(os.Chmod(filepath.Join(modules, "tool/cli.js"), 0755))
+	mustDo(os.MkdirAll(filepath.Join(modules, ".bin"), 0755))
+	mustDo(os.Symlink("../tool/cli.js", filepath.Join(modules, ".bin/tool")))
+	mustDo(os.Symlink("../../shared", filepath.Join(modules, "shared")))
+	mustDo(os.Symlink("loop-b", filepath.Join(modules, "loop-a")))
+	mustDo(os.Symlink("loop-a", filepath.Join(modules, "loop-b")))
+	mustDo(syscall.Mkfifo(filepath.Join(modules, "pipe"), 0600))
+	listener, err := net.Listen("unix", filepath.Join(modules, "server.sock"))
+	mustDo(err)
+	defer listener.Close()
+
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	mustDo(os.Chtimes(filepath.Join(modules, "tool/cli.js"), atime, mtime))
+	mustDo(os.Chtimes(filepath.Join(modules, "tool"), atime, mtime))
+
+	// A read-only directory still receives its content
+	mustDo(os.Chmod(filepath.Join(modules, "locked"), 0555))
+	t.Cleanup(func() {
+		os.Chmod(filepath.Join(modules, "locked"), 0755)
+		os.Chmod(filepath.Join(destDir, "node_modules/locked"), 0755)
+	})
+
+	cfg := &config.Config{Resources: config.Resources{Copy: []string{"node_modules"}}}
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	result := results[0]
+	if !result.Success {
+		t.Fatalf("failed to copy: %v", result.Error)
+	}
+	if !strings.Contains(result.Warning, "skipped 1 special files: node_modules/server.sock") ||
+		!strings.Contains(result.Warning, "copied 2 looping symlinks as they are: node_modules/loop-a, node_modules/loop-b") {
+		t.Errorf("unexpected warning: %q", result.Warning)
+	}
+
+	copied := filepath.Join(destDir, "node_modules")
+	links := map[string]string{
+		".bin/tool": "../tool/cli.js",
+		"shared":    filepath.Join(root, "shared"),
+		"loop-a":    "loop-b",
+	}
+	for link, want := range links {
+		if target, err := os.Readlink(filepath.Join(copied, link)); err != nil || target != want {
+			t.Errorf("expected %s to link to %s, got %q, %v", link, want, target, err)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(copied, "tool/cli.js"))
+	if err != nil {
+		t.Fatalf("expected cli.js to be copied: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected executable bits to be kept, got %v", info.Mode())
+	}
+	if !info.ModTime().Equal(mtime) || !fileAtime(info).Equal(atime) {
+		t.Errorf("expected timestamps to be kept, got mtime %v atime %v", info.ModTime(), fileAtime(info))
+	}
+	if dirInfo, err := os.Stat(filepath.Join(copied, "tool")); err != nil || !dirInfo.ModTime().Equal(mtime) {
+		t.Errorf("expected directory mtime to be kept, got %v", err)
+	}
+
+	if info, err := os.Lstat(filepath.Join(copied, "pipe")); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("expected pipe to be recreated, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(copied, "server.sock")); err == nil {
+		t.Error("expected socket to be skipped")
+	}
+
+	if info, err := os.Stat(filepath.Join(copied, "locked")); err != nil || info.Mode().Perm() != 0555 {
+		t.Errorf("expected read-only directory to be kept, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(copied, "locked/index.js")); err != nil {
+		t.Errorf("expected content of read-only directory to be copied: %v", err)
+	}
+}
+
+func TestSyncResourcesCopySymlinkLoop(t *testing.T) {
+	sourceDir, destDir := t.TempDir(), t.TempDir()
+	if err := os.Symlink("vendor", filepath.Join(sourceDir, "vendor")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Resources: config.Resources{Copy: []string{"vendor"}}}
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if results[0].Success || !strings.Contains(results[0].Error.Error(), "symlink loop: vendor") {
+		t.Errorf("expected symlink loop error, got %+v", results[0])
+	}
+}
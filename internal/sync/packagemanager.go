@@ -0,0 +1,239 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+	"github.com/fs0414/git-worktree-sync/internal/git"
+)
+
+// packageManager describes how a Node package manager lays out dependencies
+type packageManager struct {
+	name string
+	// lockfiles identify the package manager; the first one found is compared
+	lockfiles []string
+	// marker must also exist for the package manager to be detected
+	marker string
+	// linkNodeModules reports whether node_modules still works when symlinked
+	linkNodeModules bool
+	// install is the command that installs dependencies in a worktree
+	install string
+	// shared are content-addressed caches that are linked when present
+	shared []string
+	// state is install state that is copied when present and the lockfiles match
+	state []string
+}
+
+// packageManagers are checked in order by DetectPackageManager
+var packageManagers = []packageManager{
+	{
+		name:      config.PackageManagerPnpm,
+		lockfiles: []string{"pnpm-lock.yaml"},
+		// node_modules holds relative links into node_modules/.pnpm, which
+		// break or resolve to the main worktree when the directory is linked
+		install: "pnpm install --offline",
+		shared:  []string{".pnpm-store"},
+	},
+	{
+		name:      config.PackageManagerYarnPnP,
+		lockfiles: []string{"yarn.lock"},
+		marker:    ".pnp.cjs",
+		install:   "yarn install",
+		shared:    []string{".yarn/cache"},
+		state:     []string{".yarn/install-state.gz", ".pnp.cjs", ".pnp.loader.mjs"},
+	},
+	{
+		name:            config.PackageManagerYarn,
+		lockfiles:       []string{"yarn.lock"},
+		linkNodeModules: true,
+		install:         "yarn install",
+	},
+	{
+		name:            config.PackageManagerBun,
+		lockfiles:       []string{"bun.lock", "bun.lockb"},
+		linkNodeModules: true,
+		install:         "bun install",
+	},
+	{
+		name:            config.PackageManagerNpm,
+		lockfiles:       []string{"package-lock.json", "npm-shrinkwrap.json"},
+		linkNodeModules: true,
+		install:         "npm install",
+	},
+}
+
+// DetectPackageManager returns the Node package manager used in dir and its
+// lockfile, or empty strings if no lockfile is found
+func DetectPackageManager(dir string) (name, lockfile string) {
+	if pm, lockfile := detectPackageManager(dir); pm != nil {
+		return pm.name, lockfile
+	}
+	return "", ""
+}
+
+func detectPackageManager(dir string) (*packageManager, string) {
+	for i := range packageManagers {
+		pm := &packageManagers[i]
+		if lockfile := pm.lockfile(dir); lockfile != "" {
+			if pm.marker != "" && !exists(filepath.Join(dir, pm.marker)) {
+				continue
+			}
+			return pm, lockfile
+		}
+	}
+	return nil, ""
+}
+
+// lockfile returns the first of the package manager's lockfiles in dir
+func (pm *packageManager) lockfile(dir string) string {
+	for _, lockfile := range pm.lockfiles {
+		if exists(filepath.Join(dir, lockfile)) {
+			return lockfile
+		}
+	}
+	return ""
+}
+
+// resolvePackageManager returns the package manager selected by the
+// package_manager setting, detecting it from sourceDir for "auto"
+func resolvePackageManager(setting, sourceDir string) (*packageManager, string, error) {
+	switch setting {
+	case "", config.PackageManagerAuto:
+		pm, lockfile := detectPackageManager(sourceDir)
+		return pm, lockfile, nil
+	case config.PackageManagerNone:
+		return nil, "", nil
+	}
+
+	for i := range packageManagers {
+		if pm := &packageManagers[i]; pm.name == setting {
+			return pm, pm.lockfile(sourceDir), nil
+		}
+	}
+	return nil, "", fmt.Errorf("unknown package manager: %s", setting)
+}
+
+// lockfileDiverged reports whether lockfile differs between sourceDir and
+// destDir. A lockfile missing from either side counts as diverged. When rev
+// is set, the lockfile of destDir is read from that commit instead.
+func lockfileDiverged(sourceDir, destDir, rev, lockfile string) bool {
+	sourceHash, err := lockfileHash(filepath.Join(sourceDir, lockfile))
+	if err != nil {
+		return true
+	}
+
+	var destHash string
+	if rev != "" {
+		data, err := git.ShowFile(sourceDir, rev, lockfile)
+		if err != nil {
+			return true
+		}
+		destHash = contentHash(data)
+	} else {
+		destHash, err = lockfileHash(filepath.Join(destDir, lockfile))
+		if err != nil {
+			return true
+		}
+	}
+	return sourceHash != destHash
+}
+
+func lockfileHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return hashFile(path, info)
+}
+
+// applyPackageManager adapts the resources to the Node package manager of
+// the source. node_modules is held back when the package manager cannot use
+// a linked node_modules or when the lockfile of the destination differs from
+// the source; shared caches and install state are added when present.
+// It returns a plan for every resource that is held back.
+func applyPackageManager(cfg *config.Config, sourceDir, destDir, rev string, resources *expandedResources) ([]ResourcePlan, error) {
+	pm, lockfile, err := resolvePackageManager(cfg.PackageManager, sourceDir)
+	if err != nil || pm == nil {
+		return nil, err
+	}
+
+	diverged := lockfile != "" && lockfileDiverged(sourceDir, destDir, rev, lockfile)
+
+	var held []ResourcePlan
+	hold := func(resource, reason string) {
+		held = append(held, ResourcePlan{
+			Resource: resource,
+			Action:   ActionHold,
+			Reason:   reason,
+		})
+	}
+
+	filter := func(list []string) []string {
+		var kept []string
+		for _, resource := range list {
+			// Resources with their own lockfiles are handled by planResource
+			opts, _ := resources.options(cfg, resource)
+			if path.Base(filepath.ToSlash(resource)) != "node_modules" || len(opts.Lockfiles) > 0 {
+				kept = append(kept, resource)
+				continue
+			}
+
+			var reason string
+			switch {
+			case !pm.linkNodeModules:
+				reason = fmt.Sprintf("%s node_modules cannot be shared between worktrees", pm.name)
+			case diverged:
+				reason = fmt.Sprintf("%s differs from the main worktree", lockfile)
+			default:
+				kept = append(kept, resource)
+				continue
+			}
+
+			if isLinkTo(filepath.Join(destDir, resource), filepath.Join(sourceDir, resource)) {
+				hold(resource, fmt.Sprintf("%s; remove the existing link and run %s", reason, pm.install))
+			} else {
+				hold(resource, fmt.Sprintf("%s; run %s", reason, pm.install))
+			}
+		}
+		return kept
+	}
+	resources.symlink = filter(resources.symlink)
+	resources.copy = filter(resources.copy)
+
+	configured := func(resource string) bool {
+		for _, list := range [][]string{resources.symlink, resources.copy} {
+			for _, r := range list {
+				if filepath.Clean(r) == filepath.Clean(resource) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, resource := range pm.shared {
+		if !configured(resource) && exists(filepath.Join(sourceDir, resource)) {
+			resources.symlink = append(resources.symlink, resource)
+		}
+	}
+	for _, resource := range pm.state {
+		if configured(resource) || !exists(filepath.Join(sourceDir, resource)) {
+			continue
+		}
+		if diverged {
+			hold(resource, fmt.Sprintf("%s differs from the main worktree; run %s", lockfile, pm.install))
+			continue
+		}
+		resources.copy = append(resources.copy, resource)
+	}
+
+	return held, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,138 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		files    []string
+		name     string
+		lockfile string
+	}{
+		{[]string{"package-lock.json"}, config.PackageManagerNpm, "package-lock.json"},
+		{[]string{"pnpm-lock.yaml", "package-lock.json"}, config.PackageManagerPnpm, "pnpm-lock.yaml"},
+		{[]string{"yarn.lock"}, config.PackageManagerYarn, "yarn.lock"},
+		{[]string{"yarn.lock", ".pnp.cjs"}, config.PackageManagerYarnPnP, "yarn.lock"},
+		{[]string{"bun.lockb"}, config.PackageManagerBun, "bun.lockb"},
+		{[]string{"package.json"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeFiles(t, dir, tt.files)
+
+		name, lockfile := DetectPackageManager(dir)
+		if name != tt.name || lockfile != tt.lockfile {
+			t.Errorf("DetectPackageManager(%v) = %q, %q, expected %q, %q", tt.files, name, lockfile, tt.name, tt.lockfile)
+		}
+	}
+}
+
+// syncModes syncs and returns the mode and reason of each result by resource
+func syncModes(t *testing.T, cfg *config.Config, sourceDir, destDir string) (map[string]string, map[string]string) {
+	t.Helper()
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	modes := make(map[string]string)
+	reasons := make(map[string]string)
+	for _, result := range results {
+		modes[result.Resource] = result.Mode
+		reasons[result.Resource] = result.Reason
+	}
+	return modes, reasons
+}
+
+func TestSyncResourcesPackageManager(t *testing.T) {
+	cfg := &config.Config{
+		Resources: config.Resources{Symlink: []string{"node_modules", "packages/a/node_modules"}},
+	}
+
+	t.Run("npm with matching lockfile", func(t *testing.T) {
+		sourceDir, destDir := t.TempDir(), t.TempDir()
+		writeFiles(t, sourceDir, []string{"package-lock.json", "node_modules/dep/index.js", "packages/a/node_modules/dep/index.js"})
+		writeFiles(t, destDir, []string{"package-lock.json"})
+
+		modes, _ := syncModes(t, cfg, sourceDir, destDir)
+		if modes["node_modules"] != "symlink" || modes["packages/a/node_modules"] != "symlink" {
+			t.Errorf("expected node_modules to be linked, got %v", modes)
+		}
+	})
+
+	t.Run("npm with diverged lockfile", func(t *testing.T) {
+		sourceDir, destDir := t.TempDir(), t.TempDir()
+		writeFiles(t, sourceDir, []string{"package-lock.json", "node_modules/dep/index.js"})
+		if err := os.WriteFile(filepath.Join(destDir, "package-lock.json"), []byte("changed"), 0644); err != nil {
+			t.Fatalf("failed to write lockfile: %v", err)
+		}
+
+		modes, reasons := syncModes(t, cfg, sourceDir, destDir)
+		if modes["node_modules"] != "held" {
+			t.Fatalf("expected node_modules to be held, got %v", modes)
+		}
+		if !strings.Contains(reasons["node_modules"], "package-lock.json differs") || !strings.Contains(reasons["node_modules"], "npm install") {
+			t.Errorf("unexpected reason: %s", reasons["node_modules"])
+		}
+		if _, err := os.Lstat(filepath.Join(destDir, "node_modules")); err == nil {
+			t.Error("expected node_modules not to be created")
+		}
+	})
+
+	t.Run("pnpm", func(t *testing.T) {
+		sourceDir, destDir := t.TempDir(), t.TempDir()
+		writeFiles(t, sourceDir, []string{"pnpm-lock.yaml", "node_modules/.pnpm/lock.yaml", ".pnpm-store/v3/index"})
+		writeFiles(t, destDir, []string{"pnpm-lock.yaml"})
+
+		modes, reasons := syncModes(t, cfg, sourceDir, destDir)
+		if modes["node_modules"] != "held" || !strings.Contains(reasons["node_modules"], "pnpm install --offline") {
+			t.Errorf("expected pnpm node_modules to be held, got %v %v", modes, reasons)
+		}
+		if modes[".pnpm-store"] != "symlink" {
+			t.Errorf("expected .pnpm-store to be linked, got %v", modes)
+		}
+
+		// The status applies the same policy
+		report, err := CheckSyncStatus(cfg, sourceDir, destDir, false)
+		if err != nil {
+			t.Fatalf("failed to check status: %v", err)
+		}
+		states := make(map[string]string)
+		for _, rs := range report.Resources {
+			states[rs.Resource] = rs.State
+		}
+		if states["node_modules"] != StateHeld || states[".pnpm-store"] != StateSynced || !report.Synced() {
+			t.Errorf("expected node_modules held and .pnpm-store synced, got %+v", report.Resources)
+		}
+	})
+
+	t.Run("yarn pnp", func(t *testing.T) {
+		sourceDir, destDir := t.TempDir(), t.TempDir()
+		writeFiles(t, sourceDir, []string{"yarn.lock", ".pnp.cjs", ".yarn/install-state.gz", ".yarn/cache/dep.zip"})
+		writeFiles(t, destDir, []string{"yarn.lock", ".pnp.cjs"})
+
+		modes, _ := syncModes(t, cfg, sourceDir, destDir)
+		if modes[".yarn/install-state.gz"] != "copy" || modes[".yarn/cache"] != "symlink" || modes[".pnp.cjs"] != "exists" {
+			t.Errorf("unexpected modes: %v", modes)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		sourceDir, destDir := t.TempDir(), t.TempDir()
+		writeFiles(t, sourceDir, []string{"pnpm-lock.yaml", "node_modules/.pnpm/lock.yaml"})
+
+		disabled := *cfg
+		disabled.PackageManager = config.PackageManagerNone
+		modes, _ := syncModes(t, &disabled, sourceDir, destDir)
+		if modes["node_modules"] != "symlink" {
+			t.Errorf("expected node_modules to be linked, got %v", modes)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"fmt"
+	"runtime"
+	gosync "sync"
+)
+
+// DefaultJobs returns the number of concurrent jobs used when Options.Jobs
+// is not set
+func DefaultJobs() int {
+	return runtime.NumCPU()
+}
+
+// syncItem is a resource to sync and the mode to sync it with
+type syncItem struct {
+	resource string
+	mode     SyncMode
+}
+
+// syncAll syncs items on up to s.jobs goroutines and returns their results
+// in the order of items. A resource nested in an earlier one is synced after
+// it, as if the resources were synced one by one.
+func (s *syncer) syncAll(items []syncItem) []SyncResult {
+	results := make([]SyncResult, len(items))
+	done := make([]chan struct{}, len(items))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	s.progress.resources.Add(int64(len(items)))
+
+	// Items are started in order, so every item an item waits for has
+	// already been started and holds or has released its slot
+	slots := make(chan struct{}, s.jobs)
+	var wg gosync.WaitGroup
+	for i, item := range items {
+		var deps []chan struct{}
+		for j := 0; j < i; j++ {
+			if isWithin(items[j].resource, item.resource) || isWithin(item.resource, items[j].resource) {
+				deps = append(deps, done[j])
+			}
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, dep := range deps {
+				<-dep
+			}
+			if s.opts.Atomic && s.failed.Load() {
+				results[i] = SyncResult{
+					Resource: item.resource,
+					Mode:     "cancelled",
+					Error:    fmt.Errorf("not synced after an earlier failure"),
+				}
+			} else {
+				results[i] = s.apply(s.planResource(item.resource, item.mode))
+			}
+			if results[i].Failed() {
+				s.failed.Store(true)
+			}
+			s.progress.resourcesDone.Add(1)
+			close(done[i])
+			<-slots
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// filePool copies files on a fixed number of goroutines shared by all
+// resources of a sync run
+type filePool struct {
+	tasks chan func()
+	wg    gosync.WaitGroup
+}
+
+func newFilePool(workers int) *filePool {
+	p := &filePool{tasks: make(chan func())}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// run queues a task, waiting until a worker is free
+func (p *filePool) run(task func()) {
+	p.tasks <- task
+}
+
+// close stops the workers once the queued tasks are done
+func (p *filePool) close() {
+	close(p.tasks)
+	p.wg.Wait()
+}
@@ -0,0 +1,92 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+// recordingProgress keeps the stats passed to Done
+type recordingProgress struct {
+	done *ProgressStats
+}
+
+func (p *recordingProgress) Update(stats ProgressStats) {}
+func (p *recordingProgress) Done(stats ProgressStats)   { p.done = &stats }
+
+func TestSyncResourcesParallel(t *testing.T) {
+	sourceDir, destDir := t.TempDir(), t.TempDir()
+
+	var files []string
+	for i := 0; i < 50; i++ {
+		files = append(files, fmt.Sprintf("build/%d/out.js", i%5), fmt.Sprintf("build/%d/file%d.txt", i%5, i))
+	}
+	writeFiles(t, sourceDir, append(files, "build/1/debug.log", "config/app.yml", "config/master.key", "cache/a", "cache/b"))
+
+	cfg := &config.Config{
+		Resources: config.Resources{
+			Symlink: []string{"cache"},
+			// config/master.key is inside config and must be synced after it
+			Copy: []string{"build", "config", "config/master.key"},
+		},
+		Exclude: []string{"*.log"},
+	}
+
+	progress := &recordingProgress{}
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{Jobs: 4, Progress: progress})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	var order []string
+	for _, result := range results {
+		order = append(order, result.Resource+":"+result.Mode)
+	}
+	want := []string{"cache:symlink", "build:copy", "config:copy", "config/master.key:exists"}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Errorf("expected results %v, got %v", want, order)
+	}
+	if results[1].Excluded != 1 {
+		t.Errorf("expected 1 excluded entry, got %d", results[1].Excluded)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(destDir, file))
+		if err != nil || string(content) != file {
+			t.Errorf("expected %s to be copied, got %q, %v", file, content, err)
+		}
+	}
+
+	if progress.done == nil {
+		t.Fatal("expected Done to be called")
+	}
+	stats := *progress.done
+	// 55 build files and the two files in config; debug.log is excluded
+	if stats.Resources != 4 || stats.ResourcesDone != 4 || stats.Files != 57 || stats.FilesDone != stats.Files {
+		t.Errorf("unexpected final stats: %+v", stats)
+	}
+	if stats.BytesDone != stats.Bytes || stats.Bytes == 0 {
+		t.Errorf("expected all bytes to be copied, got %+v", stats)
+	}
+}
+
+func TestProgressStatsETA(t *testing.T) {
+	tests := []struct {
+		stats ProgressStats
+		want  time.Duration
+	}{
+		{ProgressStats{Bytes: 100, BytesDone: 25, Elapsed: time.Second}, 3 * time.Second},
+		{ProgressStats{Bytes: 100, BytesDone: 0, Elapsed: time.Second}, 0},
+		{ProgressStats{Bytes: 100, BytesDone: 100, Elapsed: time.Second}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.stats.ETA(); got != tt.want {
+			t.Errorf("ETA(%+v) = %v, expected %v", tt.stats, got, tt.want)
+		}
+	}
+}
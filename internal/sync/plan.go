@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+// Actions a sync takes for a resource
+const (
+	ActionLink    = "link"
+	ActionCopy    = "copy"
+	ActionReplace = "replace"
+	ActionExists  = "exists"
+	ActionSkip    = "skip"
+	ActionHold    = "hold"
+	ActionError   = "error"
+)
+
+// ResourcePlan describes what a sync does with a resource. SyncResources
+// carries out the plans it makes; PlanSync only returns them.
+type ResourcePlan struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	// Mode is how a linked, copied or replaced resource is synced:
+	// symlink or copy
+	Mode string `json:"mode,omitempty"`
+	// Reason explains why a resource is skipped, held back or fails
+	Reason string `json:"reason,omitempty"`
+	// Warning is a problem with a resource that is synced anyway
+	Warning string `json:"warning,omitempty"`
+	// Unlink is set when a link to the main worktree is removed first, so
+	// that the worktree gets a copy of its own
+	Unlink bool `json:"unlink,omitempty"`
+	// Excluded lists the paths exclude patterns leave out of a copy
+	Excluded []string `json:"excluded,omitempty"`
+	// Files and Bytes are the number and size of the files copied
+	Files int64 `json:"files,omitempty"`
+	Bytes int64 `json:"bytes,omitempty"`
+	// Install is a command run in the worktree once the resource is synced
+	Install string `json:"install,omitempty"`
+
+	mode SyncMode
+	err  error
+	// adopt is set for an existing link to the source that is missing
+	// from the manifest
+	adopt bool
+	// copier and tree copy the files of a copied resource
+	copier *copier
+	tree   *copyTree
+}
+
+// fail returns the plan of a resource that is skipped or cannot be synced
+func (p ResourcePlan) fail(action string, err error) ResourcePlan {
+	p.Action = action
+	p.Mode = ""
+	p.err = err
+	p.Reason = err.Error()
+	p.copier, p.tree = nil, nil
+	return p
+}
+
+// creates reports whether the plan creates the resource in the worktree
+func (p ResourcePlan) creates() bool {
+	return p.Action == ActionLink || p.Action == ActionCopy || p.Action == ActionReplace
+}
+
+// PlanSync returns what SyncResources would do with the same arguments,
+// without changing anything
+func PlanSync(cfg *config.Config, sourceDir, destDir string, opts Options) ([]ResourcePlan, error) {
+	s, plans, items, err := newSyncer(cfg, sourceDir, destDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		plan := s.planResource(item.resource, item.mode)
+		if plan.creates() {
+			s.planned = append(s.planned, item.resource)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// willCreate reports whether a resource planned earlier creates the
+// destination of resource or a path inside it
+func (s *syncer) willCreate(resource string) bool {
+	for _, planned := range s.planned {
+		if isWithin(planned, resource) || isWithin(resource, planned) {
+			return true
+		}
+	}
+	return false
+}
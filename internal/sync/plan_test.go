@@ -0,0 +1,136 @@
+package sync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestPlanSync(t *testing.T) {
+	sourceDir, destDir := newWorktree(t)
+	writeFiles(t, sourceDir, []string{
+		"node_modules/dep/index.js",
+		".env",
+		"config/app.yml",
+		"config/debug.log",
+		"config/master.key",
+		"vendor/gem.rb",
+		"Gemfile.lock",
+	})
+	writeFiles(t, destDir, []string{".env", "Gemfile.lock"})
+	if err := os.WriteFile(filepath.Join(destDir, "Gemfile.lock"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(sourceDir, "vendor"), filepath.Join(destDir, "vendor")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Resources: config.Resources{
+			Symlink: []string{"node_modules", "vendor", "missing"},
+			Copy:    []string{".env", "config", "config/master.key"},
+		},
+		Exclude: []string{"*.log"},
+		ResourceOptions: map[string]config.ResourceOptions{
+			"vendor": {Lockfiles: []string{"Gemfile.lock"}, OnLockfileChange: config.LockfileCopy, Install: "bundle install"},
+		},
+	}
+	opts := Options{Force: true, Jobs: 1}
+
+	before := snapshotTree(t, destDir)
+	plans, err := PlanSync(cfg, sourceDir, destDir, opts)
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	if after := snapshotTree(t, destDir); !reflect.DeepEqual(before, after) {
+		t.Errorf("expected planning to change nothing\nbefore: %v\nafter:  %v", before, after)
+	}
+
+	actions := make(map[string]string)
+	for _, plan := range plans {
+		actions[plan.Resource] = plan.Action
+	}
+	want := map[string]string{
+		"node_modules": ActionLink,
+		"vendor":       ActionCopy,
+		"missing":      ActionSkip,
+		".env":         ActionReplace,
+		"config":       ActionCopy,
+		// Created by the copy of config
+		"config/master.key": ActionReplace,
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("expected actions %v, got %v", want, actions)
+	}
+
+	byResource := make(map[string]ResourcePlan)
+	for _, plan := range plans {
+		byResource[plan.Resource] = plan
+	}
+	if vendor := byResource["vendor"]; !vendor.Unlink || vendor.Install != "bundle install" || vendor.Warning == "" {
+		t.Errorf("expected vendor to be unlinked and copied, got %+v", vendor)
+	}
+	if copied := byResource["config"]; !reflect.DeepEqual(copied.Excluded, []string{"config/debug.log"}) || copied.Files != 2 {
+		t.Errorf("expected config to copy 2 files without debug.log, got %+v", copied)
+	}
+
+	// The sync carries out the same plans
+	results, err := SyncResources(cfg, sourceDir, destDir, opts)
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	modes := map[string]string{
+		ActionLink:    "symlink",
+		ActionCopy:    "copy",
+		ActionReplace: "replaced",
+		ActionSkip:    "skip",
+	}
+	for _, result := range results {
+		if want := modes[actions[result.Resource]]; result.Mode != want {
+			t.Errorf("expected %s to be synced as %s, got %s (%v)", result.Resource, want, result.Mode, result.Error)
+		}
+	}
+}
+
+func TestPlanSyncRev(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, []string{"package-lock.json", "node_modules/dep/index.js"})
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=gws", "-c", "user.email=gws@example.com"}, args...)...)
+		cmd.Dir = sourceDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	git("init")
+	git("add", "package-lock.json")
+	git("commit", "-m", "lockfile")
+
+	// The worktree does not exist yet
+	destDir := filepath.Join(t.TempDir(), "feature")
+	cfg := &config.Config{Resources: config.Resources{Symlink: []string{"node_modules"}}}
+
+	plans, err := PlanSync(cfg, sourceDir, destDir, Options{Rev: "HEAD"})
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	if len(plans) != 1 || plans[0].Action != ActionLink {
+		t.Errorf("expected node_modules to be linked, got %+v", plans)
+	}
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "package-lock.json"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plans, err = PlanSync(cfg, sourceDir, destDir, Options{Rev: "HEAD"})
+	if err != nil {
+		t.Fatalf("failed to plan: %v", err)
+	}
+	if len(plans) != 1 || plans[0].Action != ActionHold {
+		t.Errorf("expected node_modules to be held back, got %+v", plans)
+	}
+}
@@ -0,0 +1,96 @@
+package sync
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ProgressStats describes how far a sync run has progressed. Files and
+// bytes are counted for copied resources only and their totals grow as
+// resources are scanned.
+type ProgressStats struct {
+	Resources     int
+	ResourcesDone int
+	Files         int64
+	FilesDone     int64
+	Bytes         int64
+	BytesDone     int64
+	Elapsed       time.Duration
+}
+
+// ETA estimates the time left from the rate at which bytes were copied so
+// far. It returns 0 when there is nothing to estimate from.
+func (p ProgressStats) ETA() time.Duration {
+	if p.BytesDone == 0 || p.Bytes <= p.BytesDone {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * float64(p.Bytes-p.BytesDone) / float64(p.BytesDone))
+}
+
+// Progress receives updates while resources are synced. Update is called
+// periodically from a single goroutine, and Done once after the last update.
+type Progress interface {
+	Update(stats ProgressStats)
+	Done(stats ProgressStats)
+}
+
+// progressInterval is how often Progress.Update is called
+const progressInterval = 100 * time.Millisecond
+
+// progressTracker counts the work done by the workers of a sync run
+type progressTracker struct {
+	start         time.Time
+	resources     atomic.Int64
+	resourcesDone atomic.Int64
+	files         atomic.Int64
+	filesDone     atomic.Int64
+	bytes         atomic.Int64
+	bytesDone     atomic.Int64
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{start: time.Now()}
+}
+
+// addFiles adds files found in a resource to the totals
+func (t *progressTracker) addFiles(files, bytes int64) {
+	t.files.Add(files)
+	t.bytes.Add(bytes)
+}
+
+// fileDone records a copied file
+func (t *progressTracker) fileDone(size int64) {
+	t.filesDone.Add(1)
+	t.bytesDone.Add(size)
+}
+
+func (t *progressTracker) stats() ProgressStats {
+	return ProgressStats{
+		Resources:     int(t.resources.Load()),
+		ResourcesDone: int(t.resourcesDone.Load()),
+		Files:         t.files.Load(),
+		FilesDone:     t.filesDone.Load(),
+		Bytes:         t.bytes.Load(),
+		BytesDone:     t.bytesDone.Load(),
+		Elapsed:       time.Since(t.start),
+	}
+}
+
+// report sends updates to progress until stop is closed, then calls
+// progress.Done and closes done
+func (t *progressTracker) report(progress Progress, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			progress.Update(t.stats())
+		case <-stop:
+			progress.Done(t.stats())
+			return
+		}
+	}
+}
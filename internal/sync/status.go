@@ -0,0 +1,239 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+	"github.com/fs0414/git-worktree-sync/internal/glob"
+)
+
+// Resource states reported by CheckSyncStatus
+const (
+	StateSynced    = "synced"
+	StateMissing   = "missing"
+	StateBroken    = "broken"
+	StateModified  = "modified"
+	StateStale     = "stale"
+	StateUnmanaged = "unmanaged"
+	// StateHeld marks a resource that sync deliberately leaves out, such as
+	// node_modules of a package manager that cannot share it
+	StateHeld = "held"
+)
+
+// Kinds of filesystem entries reported by CheckSyncStatus
+const (
+	KindSymlink = "symlink"
+	KindFile    = "file"
+	KindDir     = "dir"
+	KindOther   = "other"
+	KindMissing = "missing"
+)
+
+// ResourceStatus is the sync state of a single resource in a worktree
+type ResourceStatus struct {
+	Resource string
+	State    string
+	Detail   string
+	// ExpectedMode is the sync mode configured for the resource (symlink or copy)
+	ExpectedMode string
+	// Kind is what actually exists at the destination
+	Kind string
+	// LinkTarget is the symlink target if Kind is symlink
+	LinkTarget string
+	// PointsToMain reports whether the symlink points into the main worktree
+	PointsToMain bool
+}
+
+// StatusReport is the sync state of all configured resources in a worktree
+type StatusReport struct {
+	Resources []ResourceStatus
+}
+
+// Synced reports whether every resource is synced or held back by design
+func (r *StatusReport) Synced() bool {
+	for _, rs := range r.Resources {
+		if rs.State != StateSynced && rs.State != StateHeld {
+			return false
+		}
+	}
+	return true
+}
+
+// Counts returns the number of resources in each state
+func (r *StatusReport) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, rs := range r.Resources {
+		counts[rs.State]++
+	}
+	return counts
+}
+
+// CheckSyncStatus checks the resources in the destination against the
+// source and the manifest recorded by the last sync. Copies are checked by
+// file sizes and modification times; with verify, their contents are
+// compared with the source as well.
+func CheckSyncStatus(cfg *config.Config, sourceDir, destDir string, verify bool) (*StatusReport, error) {
+	manifest, err := LoadManifest(destDir)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := glob.NewMatcher(cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	check := &statusCheck{sourceDir: sourceDir, destDir: destDir, exclude: exclude, verify: verify}
+
+	resources, err := expandResources(cfg, sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	configured := make(map[string]string)
+	for _, resource := range resources.symlink {
+		configured[resource] = "symlink"
+	}
+	for _, resource := range resources.copy {
+		configured[resource] = "copy"
+	}
+
+	// The same package manager policy as SyncResources decides which
+	// resources are synced at all
+	held, err := applyPackageManager(cfg, sourceDir, destDir, "", resources)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StatusReport{}
+	for _, plan := range held {
+		// Install state added by the package manager is copied
+		mode := configured[plan.Resource]
+		if mode == "" {
+			mode = "copy"
+		}
+		status := check.resource(plan.Resource, mode, ManifestEntry{}, false)
+		status.State = StateHeld
+		status.Detail = plan.Reason
+		report.Resources = append(report.Resources, status)
+	}
+	for _, resource := range resources.symlink {
+		entry, recorded := manifest.Resources[resource]
+		status := check.resource(resource, "symlink", entry, recorded)
+		if opts, ok := resources.options(cfg, resource); ok && len(opts.Lockfiles) > 0 {
+			checkLockfiles(&status, opts, sourceDir, destDir)
+		}
+		report.Resources = append(report.Resources, status)
+	}
+	for _, resource := range resources.copy {
+		entry, recorded := manifest.Resources[resource]
+		status := check.resource(resource, "copy", entry, recorded)
+		if opts, ok := resources.options(cfg, resource); ok && len(opts.Lockfiles) > 0 {
+			checkLockfiles(&status, opts, sourceDir, destDir)
+		}
+		report.Resources = append(report.Resources, status)
+	}
+
+	return report, nil
+}
+
+// statusCheck holds what checking a resource needs besides the resource
+type statusCheck struct {
+	sourceDir string
+	destDir   string
+	exclude   *glob.Matcher
+	verify    bool
+}
+
+func (c *statusCheck) resource(resource, expectedMode string, entry ManifestEntry, recorded bool) ResourceStatus {
+	sourceDir := c.sourceDir
+	sourcePath := filepath.Join(sourceDir, resource)
+	destPath := filepath.Join(c.destDir, resource)
+	status := ResourceStatus{
+		Resource:     resource,
+		ExpectedMode: expectedMode,
+	}
+
+	destInfo, err := os.Lstat(destPath)
+	if err != nil {
+		status.State = StateMissing
+		status.Kind = KindMissing
+		return status
+	}
+
+	switch {
+	case destInfo.Mode()&os.ModeSymlink != 0:
+		status.Kind = KindSymlink
+	case destInfo.IsDir():
+		status.Kind = KindDir
+	case destInfo.Mode().IsRegular():
+		status.Kind = KindFile
+	default:
+		status.Kind = KindOther
+	}
+
+	// Symlinks describe themselves: they are synced as long as they resolve
+	// to the source
+	if status.Kind == KindSymlink {
+		status.LinkTarget, _ = os.Readlink(destPath)
+		target := status.LinkTarget
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(destPath), target)
+		}
+		status.PointsToMain = isWithin(sourceDir, target)
+
+		if _, err := os.Stat(destPath); err != nil {
+			status.State = StateBroken
+			status.Detail = "symlink target does not exist"
+			return status
+		}
+		if !isLinkTo(destPath, sourcePath) {
+			status.State = StateBroken
+			status.Detail = "symlink does not point to the source"
+			return status
+		}
+		status.State = StateSynced
+		return status
+	}
+
+	if !recorded || entry.Mode != "copy" {
+		status.State = StateUnmanaged
+		status.Detail = "not created by gws"
+		return status
+	}
+
+	destFp, err := fingerprint(destPath)
+	if err != nil {
+		status.State = StateModified
+		status.Detail = "changed since last sync"
+		return status
+	}
+
+	sourceFp, err := fingerprint(sourcePath)
+	if err != nil {
+		status.State = StateStale
+		status.Detail = "source no longer exists"
+		return status
+	}
+
+	// Fingerprints only cover metadata, so a mismatch may be a file that was
+	// touched or rewritten unchanged; the contents decide
+	destChanged := destFp.hash != entry.DestHash
+	sourceChanged := sourceFp.hash != entry.Hash
+	if destChanged || sourceChanged || c.verify {
+		same, err := sameContents(sourcePath, destPath, filepath.ToSlash(filepath.Clean(resource)), c.exclude, c.verify)
+		switch {
+		case err == nil && same:
+		case destChanged || !sourceChanged:
+			status.State = StateModified
+			status.Detail = "changed since last sync"
+			return status
+		default:
+			status.State = StateStale
+			status.Detail = "source changed since last sync"
+			return status
+		}
+	}
+
+	status.State = StateSynced
+	return status
+}
@@ -0,0 +1,170 @@
+package sync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func TestCheckSyncStatus(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	if output, err := exec.Command("git", "init", destDir).CombinedOutput(); err != nil {
+		t.Fatalf("failed to init repository: %v\n%s", err, output)
+	}
+
+	writeFiles(t, sourceDir, []string{
+		"node_modules/pkg/index.js",
+		"vendor/lib.rb",
+		".env",
+		"config/app.yml",
+		"config/master.key",
+		"certs/app.pem",
+	})
+
+	cfg := &config.Config{
+		Resources: config.Resources{
+			Symlink: []string{"node_modules", "vendor"},
+			Copy:    []string{".env", "config/app.yml", "config/master.key", ".env.local", "certs"},
+		},
+	}
+
+	if _, err := SyncResources(cfg, sourceDir, destDir, Options{}); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	report, err := CheckSyncStatus(cfg, sourceDir, destDir, false)
+	if err != nil {
+		t.Fatalf("failed to check status: %v", err)
+	}
+	if report.Counts()[StateSynced] != 6 || report.Counts()[StateMissing] != 1 {
+		t.Fatalf("expected 6 synced and 1 missing, got %+v", report.Resources)
+	}
+
+	// Break the synced resources in different ways
+	if err := os.Remove(filepath.Join(destDir, "vendor")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(sourceDir, "missing"), filepath.Join(destDir, "vendor")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(destDir, ".env"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "config/app.yml"), []byte("updated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(destDir, ".env.local"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// An edit inside a copied directory that keeps the file size
+	if err := os.WriteFile(filepath.Join(destDir, "certs/app.pem"), []byte("certs/new.pem"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = CheckSyncStatus(cfg, sourceDir, destDir, false)
+	if err != nil {
+		t.Fatalf("failed to check status: %v", err)
+	}
+	if report.Synced() {
+		t.Error("expected report not to be synced")
+	}
+
+	expected := map[string]string{
+		"node_modules":      StateSynced,
+		"vendor":            StateBroken,
+		".env":              StateModified,
+		"config/app.yml":    StateStale,
+		"config/master.key": StateSynced,
+		".env.local":        StateUnmanaged,
+		"certs":             StateModified,
+	}
+	expectedKinds := map[string]string{
+		"node_modules":      KindSymlink,
+		"vendor":            KindSymlink,
+		".env":              KindFile,
+		"config/app.yml":    KindFile,
+		"config/master.key": KindFile,
+		".env.local":        KindFile,
+		"certs":             KindDir,
+	}
+	for _, rs := range report.Resources {
+		if rs.State != expected[rs.Resource] {
+			t.Errorf("%s: expected %s, got %s", rs.Resource, expected[rs.Resource], rs.State)
+		}
+		if rs.Kind != expectedKinds[rs.Resource] {
+			t.Errorf("%s: expected kind %s, got %s", rs.Resource, expectedKinds[rs.Resource], rs.Kind)
+		}
+		if rs.Kind == KindSymlink && !rs.PointsToMain {
+			t.Errorf("%s: expected symlink to point into the main worktree", rs.Resource)
+		}
+	}
+	if report.Resources[0].ExpectedMode != "symlink" || report.Resources[2].ExpectedMode != "copy" {
+		t.Errorf("unexpected expected modes: %+v", report.Resources)
+	}
+}
+
+func TestCheckSyncStatusContents(t *testing.T) {
+	sourceDir, destDir := newWorktree(t)
+	writeFiles(t, sourceDir, []string{"certs/app.pem", "certs/ca.pem", "certs/debug.log"})
+
+	cfg := &config.Config{
+		Resources: config.Resources{Copy: []string{"certs"}},
+		Exclude:   []string{"*.log"},
+	}
+	if _, err := SyncResources(cfg, sourceDir, destDir, Options{}); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	state := func(verify bool) string {
+		t.Helper()
+		report, err := CheckSyncStatus(cfg, sourceDir, destDir, verify)
+		if err != nil {
+			t.Fatalf("failed to check status: %v", err)
+		}
+		return report.Resources[0].State
+	}
+	if got := state(true); got != StateSynced {
+		t.Fatalf("expected a fresh copy without excluded files to be synced, got %s", got)
+	}
+
+	// Touching a file changes its fingerprint but not its contents
+	later := time.Now().Add(time.Hour)
+	pem := filepath.Join(destDir, "certs/app.pem")
+	if err := os.Chtimes(pem, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := state(false); got != StateSynced {
+		t.Errorf("expected a touched file to be synced, got %s", got)
+	}
+
+	// An edit that keeps size and modification time is only found by verify
+	info, err := os.Stat(filepath.Join(sourceDir, "certs/app.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pem, []byte("certs/new.pem"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(pem, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := state(false); got != StateSynced {
+		t.Errorf("expected the edit to go unnoticed without verify, got %s", got)
+	}
+	if got := state(true); got != StateModified {
+		t.Errorf("expected verify to find the edit, got %s", got)
+	}
+
+	// A deleted file is found without reading contents
+	if err := os.Remove(filepath.Join(destDir, "certs/ca.pem")); err != nil {
+		t.Fatal(err)
+	}
+	if got := state(false); got != StateModified {
+		t.Errorf("expected a deleted file to be modified, got %s", got)
+	}
+}
@@ -2,11 +2,16 @@ package sync
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	gosync "sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fs0414/git-worktree-sync/internal/config"
+	"github.com/fs0414/git-worktree-sync/internal/git"
+	"github.com/fs0414/git-worktree-sync/internal/glob"
 )
 
 // SyncMode represents how resources should be synced
@@ -23,97 +28,599 @@ type SyncResult struct {
 	Mode     string
 	Success  bool
 	Error    error
+	Excluded int
+	// Backup is where the previous destination was moved when it was replaced
+	Backup string
+	// Reason explains why a resource was held back (mode "held")
+	Reason string
+	// Warning is a problem with a resource that was synced
+	Warning string
+	// Install is a command to run in the worktree to rebuild the resource
+	Install string
+	// Strategy lists the strategies used to copy the files of the resource
+	Strategy string
+	// RolledBack is set when the changes made for the resource were undone
+	RolledBack bool
+}
+
+// Failed reports whether the resource failed to sync. Missing sources and
+// cancelled resources are not failures.
+func (r SyncResult) Failed() bool {
+	return !r.Success && r.Mode != "skip" && r.Mode != "cancelled"
+}
+
+// addWarning appends a warning to the result
+func (r *SyncResult) addWarning(warning string) {
+	if r.Warning != "" {
+		warning = r.Warning + "; " + warning
+	}
+	r.Warning = warning
+}
+
+// Options controls how resources are synced
+type Options struct {
+	// Copy syncs symlink resources by copying them instead
+	Copy bool
+	// Force replaces existing destinations, backing them up first
+	Force bool
+	// Jobs is the number of resources and files synced concurrently;
+	// DefaultJobs is used when it is not positive
+	Jobs int
+	// Progress receives progress updates when set
+	Progress Progress
+	// Atomic rolls back every change when a resource fails to sync and
+	// returns a *RollbackError
+	Atomic bool
+	// Transaction records the changes made by the sync when set, so that
+	// the caller can roll them back later
+	Transaction *Transaction
+	// Rev is the commit a worktree that is not checked out yet will check
+	// out. PlanSync reads the worktree's lockfiles from it.
+	Rev string
+}
+
+// BackupDirName is the directory inside a worktree's git dir that holds
+// destinations replaced by a forced sync
+const BackupDirName = "gws-backup"
+
+// syncer holds the state shared by all resources of a single sync run
+type syncer struct {
+	cfg       *config.Config
+	sourceDir string
+	destDir   string
+	opts      Options
+	exclude   *glob.Matcher
+	backupDir string
+	manifest  *Manifest
+	resources *expandedResources
+	jobs      int
+	files     *filePool
+	progress  *progressTracker
+	tx        *Transaction
+	// failed is set once a resource fails to sync
+	failed atomic.Bool
+	// mu guards manifest and backupDir, which are shared by the workers
+	mu gosync.Mutex
+	// planned lists the resources a dry run has planned to create
+	planned []string
+}
+
+// newSyncer prepares a sync run. It returns the plans of the resources that
+// are held back or matched nothing, and the resources to sync.
+func newSyncer(cfg *config.Config, sourceDir, destDir string, opts Options) (*syncer, []ResourcePlan, []syncItem, error) {
+	// Every resource would replace itself, with --force moving the source
+	// into the backup directory
+	if SameDir(sourceDir, destDir) {
+		return nil, nil, nil, fmt.Errorf("cannot sync the main worktree into itself: %s", destDir)
+	}
+
+	exclude, err := glob.NewMatcher(cfg.Exclude)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
+	manifest, err := LoadManifest(destDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	resources, err := expandResources(cfg, sourceDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	s := &syncer{
+		cfg:       cfg,
+		sourceDir: sourceDir,
+		destDir:   destDir,
+		opts:      opts,
+		exclude:   exclude,
+		manifest:  manifest,
+		resources: resources,
+		jobs:      opts.Jobs,
+		progress:  newProgressTracker(),
+		tx:        opts.Transaction,
+	}
+	if s.jobs <= 0 {
+		s.jobs = DefaultJobs()
+	}
+	if s.tx == nil {
+		s.tx = NewTransaction()
+	}
+
+	plans, err := applyPackageManager(cfg, sourceDir, destDir, opts.Rev, resources)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Patterns that match nothing are reported like missing sources
+	for _, pattern := range resources.unmatched {
+		plan := ResourcePlan{Resource: pattern}
+		plans = append(plans, plan.fail(ActionSkip, fmt.Errorf("pattern matched nothing: %s", pattern)))
+	}
+
+	// If copy mode is forced, treat symlink resources as copy
+	symlinkMode := SyncModeSymlink
+	if opts.Copy {
+		symlinkMode = SyncModeCopy
+	}
+	var items []syncItem
+	for _, resource := range resources.symlink {
+		items = append(items, syncItem{resource, symlinkMode})
+	}
+	for _, resource := range resources.copy {
+		items = append(items, syncItem{resource, SyncModeCopy})
+	}
+
+	return s, plans, items, nil
 }
 
 // SyncResources synchronizes resources from source to destination based on config
-func SyncResources(cfg *config.Config, sourceDir, destDir string, forceCopy bool) ([]SyncResult, error) {
+func SyncResources(cfg *config.Config, sourceDir, destDir string, opts Options) ([]SyncResult, error) {
 	var results []SyncResult
 
-	// Sync symlink resources
-	if !forceCopy {
-		for _, resource := range cfg.Resources.Symlink {
-			result := syncResource(resource, sourceDir, destDir, SyncModeSymlink)
-			results = append(results, result)
+	s, plans, items, err := newSyncer(cfg, sourceDir, destDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	restoreManifest, err := snapshotManifest(destDir)
+	if err != nil {
+		return nil, err
+	}
+	s.tx.record("", "Restored sync manifest", restoreManifest)
+
+	for _, plan := range plans {
+		results = append(results, s.apply(plan))
+	}
+
+	s.files = newFilePool(s.jobs)
+	if opts.Progress != nil {
+		stop, done := make(chan struct{}), make(chan struct{})
+		go s.progress.report(opts.Progress, stop, done)
+		defer func() {
+			close(stop)
+			<-done
+		}()
+	}
+	results = append(results, s.syncAll(items)...)
+	s.files.close()
+
+	if opts.Atomic && s.failed.Load() {
+		return results, s.rollback(results)
+	}
+
+	if err := s.manifest.Save(destDir); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
+
+// expandedResources are the configured resources with glob patterns expanded
+type expandedResources struct {
+	symlink []string
+	copy    []string
+	// unmatched lists patterns that matched nothing in the source
+	unmatched []string
+	// entries maps each resource to the config entry it was expanded from
+	entries map[string]string
+}
+
+// options returns the resource options of a resource
+func (r *expandedResources) options(cfg *config.Config, resource string) (config.ResourceOptions, bool) {
+	entry, ok := r.entries[resource]
+	if !ok {
+		entry = resource
+	}
+	return cfg.OptionsFor(entry, resource)
+}
+
+// expandResources expands glob patterns in the configured resources against
+// sourceDir. Entries without glob characters are kept as they are. A path
+// matched by several entries is synced once, by the first entry that
+// matches it; symlink entries come before copy entries.
+func expandResources(cfg *config.Config, sourceDir string) (*expandedResources, error) {
+	expanded := &expandedResources{entries: make(map[string]string)}
+	seen := make(map[string]bool)
+
+	expand := func(entries []string) ([]string, error) {
+		var resources []string
+		for _, entry := range entries {
+			matches := []string{entry}
+			if glob.HasMeta(entry) {
+				var err error
+				matches, err = glob.Expand(sourceDir, entry)
+				if err != nil {
+					return nil, err
+				}
+				if len(matches) == 0 {
+					expanded.unmatched = append(expanded.unmatched, entry)
+				}
+			}
+
+			for _, resource := range matches {
+				key := filepath.Clean(resource)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				expanded.entries[resource] = entry
+				resources = append(resources, resource)
+			}
 		}
-	} else {
-		// If force copy, treat symlink resources as copy
-		for _, resource := range cfg.Resources.Symlink {
-			result := syncResource(resource, sourceDir, destDir, SyncModeCopy)
-			results = append(results, result)
+		return resources, nil
+	}
+
+	var err error
+	if expanded.symlink, err = expand(cfg.Resources.Symlink); err != nil {
+		return nil, err
+	}
+	if expanded.copy, err = expand(cfg.Resources.Copy); err != nil {
+		return nil, err
+	}
+
+	return expanded, nil
+}
+
+// planSync decides how a resource is synced. With unlink, a destination
+// that links to the source is removed first and does not count as existing.
+func (s *syncer) planSync(resource string, mode SyncMode, unlink bool) ResourcePlan {
+	sourcePath := filepath.Join(s.sourceDir, resource)
+	destPath := filepath.Join(s.destDir, resource)
+
+	plan := ResourcePlan{Resource: resource, mode: mode}
+	plan.Unlink = unlink && isLinkTo(destPath, sourcePath)
+
+	// Check if source exists
+	if _, err := os.Lstat(sourcePath); err != nil {
+		if os.IsNotExist(err) {
+			return plan.fail(ActionSkip, fmt.Errorf("source does not exist: %s", resource))
 		}
+		return plan.fail(ActionError, fmt.Errorf("failed to stat source: %w", err))
 	}
 
-	// Sync copy resources
-	for _, resource := range cfg.Resources.Copy {
-		result := syncResource(resource, sourceDir, destDir, SyncModeCopy)
-		results = append(results, result)
+	// Check if destination already exists
+	replace := false
+	if !plan.Unlink && (exists(destPath) || s.willCreate(resource)) {
+		upToDate := mode == SyncModeSymlink && isLinkTo(destPath, sourcePath)
+		if !s.opts.Force || upToDate {
+			// Destination exists, skip. Links created before the manifest
+			// existed are adopted.
+			plan.Action = ActionExists
+			plan.adopt = upToDate
+			return plan
+		}
+		replace = true
 	}
 
-	return results, nil
+	if mode == SyncModeSymlink {
+		plan.Action, plan.Mode = ActionLink, "symlink"
+	} else {
+		plan.Action, plan.Mode = ActionCopy, "copy"
+		if err := s.planCopy(&plan, sourcePath); err != nil {
+			return plan.fail(ActionError, err)
+		}
+	}
+	if replace {
+		plan.Action = ActionReplace
+	}
+	return plan
 }
 
-func syncResource(resource, sourceDir, destDir string, mode SyncMode) SyncResult {
-	sourcePath := filepath.Join(sourceDir, resource)
-	destPath := filepath.Join(destDir, resource)
+// planCopy lists the files copied for a resource
+func (s *syncer) planCopy(plan *ResourcePlan, sourcePath string) error {
+	// A resource that is itself a symlink is copied from its target
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		if isLoop(err) {
+			err = fmt.Errorf("symlink loop: %s", plan.Resource)
+		}
+		return fmt.Errorf("failed to stat source: %w", err)
+	}
 
+	opts, _ := s.resources.options(s.cfg, plan.Resource)
+	c := &copier{
+		exclude:  s.exclude,
+		root:     s.sourceDir,
+		strategy: opts.CopyStrategy,
+		files:    s.files,
+		progress: s.progress,
+	}
+	tree := &copyTree{}
+	switch {
+	case sourceInfo.IsDir():
+		if err := c.scanDir(sourcePath, "", filepath.ToSlash(filepath.Clean(plan.Resource)), tree); err != nil {
+			return err
+		}
+	case sourceInfo.Mode().IsRegular():
+		tree.files = append(tree.files, copyEntry{source: sourcePath, info: sourceInfo})
+	default:
+		return fmt.Errorf("cannot copy special file: %s", plan.Resource)
+	}
+
+	plan.copier, plan.tree = c, tree
+	plan.Excluded = c.excluded
+	plan.Files, plan.Bytes = tree.size()
+	return nil
+}
+
+// apply carries out the plan of a resource
+func (s *syncer) apply(plan ResourcePlan) SyncResult {
 	result := SyncResult{
-		Resource: resource,
+		Resource: plan.Resource,
 		Success:  false,
 	}
 
-	// Check if source exists
-	sourceInfo, err := os.Lstat(sourcePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			result.Error = fmt.Errorf("source does not exist: %s", resource)
-			result.Mode = "skip"
+	if plan.Unlink {
+		if err := s.unlinkFromSource(plan.Resource); err != nil {
+			result.Error = err
+			result.Mode = "error"
 			return result
 		}
-		result.Error = fmt.Errorf("failed to stat source: %w", err)
-		result.Mode = "error"
-		return result
 	}
 
-	// Check if destination already exists
-	if _, err := os.Lstat(destPath); err == nil {
-		// Destination exists, skip
+	switch plan.Action {
+	case ActionSkip:
+		result.Mode = "skip"
+		result.Error = plan.err
+	case ActionError:
+		result.Mode = "error"
+		result.Error = plan.err
+	case ActionHold:
+		result.Mode = "held"
+		result.Reason = plan.Reason
 		result.Success = true
+	case ActionExists:
+		if plan.adopt {
+			s.mu.Lock()
+			_, ok := s.manifest.Resources[plan.Resource]
+			s.mu.Unlock()
+			if !ok {
+				s.record(plan.Resource, "symlink", filepath.Join(s.sourceDir, plan.Resource), filepath.Join(s.destDir, plan.Resource))
+			}
+		}
 		result.Mode = "exists"
-		return result
+		result.Success = true
+	default:
+		s.place(plan, &result)
+	}
+
+	if plan.Warning != "" {
+		result.addWarning(plan.Warning)
+	}
+	// Only a fresh copy needs to be brought up to date
+	if result.Success && result.Mode != "exists" {
+		result.Install = plan.Install
 	}
+	return result
+}
+
+// place links or copies a resource into the destination
+func (s *syncer) place(plan ResourcePlan, result *SyncResult) {
+	resource := plan.Resource
+	sourcePath := filepath.Join(s.sourceDir, resource)
+	destPath := filepath.Join(s.destDir, resource)
 
 	// Ensure parent directory exists
 	parentDir := filepath.Dir(destPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		result.Error = fmt.Errorf("failed to create parent directory: %w", err)
+	if err := s.mkdirAll(resource, parentDir); err != nil {
+		result.Error = err
 		result.Mode = "error"
-		return result
+		return
 	}
 
+	// Build the resource next to its destination, so that a failed sync
+	// leaves nothing behind and the destination appears in a single rename
+	removeStaged(parentDir, filepath.Base(destPath))
+	stage, err := os.MkdirTemp(parentDir, stagePrefix+filepath.Base(destPath)+"-")
+	if err != nil {
+		result.Error = fmt.Errorf("failed to create staging directory: %w", err)
+		result.Mode = "error"
+		return
+	}
+	defer removeAll(stage)
+	staged := filepath.Join(stage, filepath.Base(destPath))
+
 	// Perform sync based on mode
-	if mode == SyncModeSymlink {
-		result.Mode = "symlink"
-		if err := createSymlink(sourcePath, destPath); err != nil {
+	result.Mode = plan.Mode
+	if plan.mode == SyncModeSymlink {
+		if err := createSymlink(sourcePath, staged); err != nil {
 			result.Error = err
-			return result
+			return
 		}
 	} else {
-		result.Mode = "copy"
-		if sourceInfo.IsDir() {
-			if err := copyDir(sourcePath, destPath); err != nil {
-				result.Error = err
-				return result
-			}
-		} else {
-			if err := copyFile(sourcePath, destPath); err != nil {
-				result.Error = err
-				return result
+		c := plan.copier
+		err := c.copyTree(plan.tree, staged)
+		result.Excluded = len(plan.Excluded)
+		result.Strategy = c.strategies()
+		result.Warning = c.warnings()
+		if err != nil {
+			result.Error = err
+			return
+		}
+	}
+
+	// Move the existing destination out of the way
+	if plan.Action == ActionReplace {
+		backup, err := s.backup(resource, destPath)
+		if err != nil {
+			result.Error = err
+			result.Mode = "error"
+			return
+		}
+		result.Backup = backup
+	}
+
+	if err := os.Rename(staged, destPath); err != nil {
+		result.Error = fmt.Errorf("failed to move %s into place: %w", resource, err)
+		if result.Backup != "" {
+			if restoreErr := moveTree(result.Backup, destPath); restoreErr == nil {
+				result.Backup = ""
 			}
 		}
+		return
+	}
+
+	if result.Backup != "" {
+		backup := result.Backup
+		s.tx.record(resource, "Restored "+resource+" from its backup", func() error {
+			if err := removeAll(destPath); err != nil {
+				return err
+			}
+			if err := moveTree(backup, destPath); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", resource, err)
+			}
+			return nil
+		})
+		result.Mode = "replaced"
+	} else {
+		s.tx.record(resource, "Removed "+resource, func() error {
+			return removeAll(destPath)
+		})
 	}
 
+	// The manifest records how the resource was synced, not that it replaced
+	// something
+	s.record(resource, plan.Mode, sourcePath, destPath)
+
 	result.Success = true
-	return result
+}
+
+// stagePrefix starts the names of the directories resources are built in
+// before they are moved into place
+const stagePrefix = ".gws-tmp-"
+
+// removeStaged removes staging directories of the resource named base that
+// an interrupted sync left in dir
+func removeStaged(dir, base string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() && strings.HasPrefix(name, stagePrefix+base+"-") && !strings.Contains(name[len(stagePrefix+base+"-"):], "-") {
+			removeAll(filepath.Join(dir, name))
+		}
+	}
+}
+
+// mkdirAll creates dir and its missing parents, recording the topmost
+// directory it creates in the transaction
+func (s *syncer) mkdirAll(resource, dir string) error {
+	// Directories shared by several resources are created and recorded
+	// before any resource places content in them
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := ""
+	for missing := dir; ; missing = filepath.Dir(missing) {
+		if _, err := os.Lstat(missing); err == nil || filepath.Dir(missing) == missing {
+			break
+		}
+		created = missing
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	if created != "" {
+		rel, err := filepath.Rel(s.destDir, created)
+		if err != nil {
+			rel = created
+		}
+		s.tx.record(resource, "Removed directory "+filepath.ToSlash(rel), func() error {
+			removeEmptyDirs(created)
+			return nil
+		})
+	}
+	return nil
+}
+
+// record adds a synced resource to the manifest. A resource whose state
+// cannot be read is left out so that it is reported as unmanaged.
+func (s *syncer) record(resource, mode, sourcePath, destPath string) {
+	entry, err := newManifestEntry(resource, mode, sourcePath, destPath)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		delete(s.manifest.Resources, resource)
+		return
+	}
+	s.manifest.Resources[resource] = entry
+}
+
+// backup moves an existing destination into the worktree's backup directory
+// and returns its new location
+func (s *syncer) backup(resource, destPath string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.backupDir == "" {
+		gitDir, err := git.GetGitDir(s.destDir)
+		if err != nil {
+			return "", err
+		}
+		timestamp := time.Now().Format("20060102-150405")
+		s.backupDir = filepath.Join(gitDir, BackupDirName, timestamp)
+
+		// Runs after the backups were restored and leaves any that were not
+		backupDir := s.backupDir
+		s.tx.record("", "Removed backup directory", func() error {
+			removeEmptyDirs(backupDir)
+			os.Remove(filepath.Dir(backupDir))
+			return nil
+		})
+	}
+
+	backupPath := filepath.Join(s.backupDir, resource)
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	if err := moveTree(destPath, backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", resource, err)
+	}
+
+	return backupPath, nil
+}
+
+// isLinkTo reports whether path is a symlink pointing at target
+func isLinkTo(path, target string) bool {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return false
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+
+	return filepath.Clean(link) == absTarget
 }
 
 func createSymlink(source, dest string) error {
@@ -130,47 +637,141 @@ func createSymlink(source, dest string) error {
 	return nil
 }
 
-func copyFile(source, dest string) error {
-	sourceFile, err := os.Open(source)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
+// copier copies files and directory trees, filtering paths against exclude
+// patterns. Files are copied on the shared file pool.
+type copier struct {
+	exclude *glob.Matcher
+	// excluded lists the paths left out by exclude patterns
+	excluded []string
+	// root is the worktree the resource is copied from
+	root string
+	// strategy is the copy_strategy of the resource being copied
+	strategy string
+	files    *filePool
+	progress *progressTracker
+	// special lists special files that could not be recreated and loops
+	// symlinks that are part of a loop
+	special []string
+	loops   []string
+
+	// mu guards the fields below, which are updated by the file workers
+	mu gosync.Mutex
+	// used records the strategies used for the files copied so far
+	used map[string]bool
+	// noReflink and noCopyFileRange are set once the filesystem rejects them
+	noReflink       bool
+	noCopyFileRange bool
+	// err is the first error returned by a file copy
+	err error
+}
+
+// copyTree lists the entries of a directory tree to copy
+type copyTree struct {
+	dirs     []copyEntry
+	files    []copyEntry
+	symlinks []copyEntry
+	fifos    []copyEntry
+}
+
+// size returns the number and total size of the files in the tree
+func (t *copyTree) size() (int64, int64) {
+	var size int64
+	for _, file := range t.files {
+		size += file.info.Size()
 	}
-	defer sourceFile.Close()
+	return int64(len(t.files)), size
+}
 
-	destFile, err := os.Create(dest)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+type copyEntry struct {
+	source string
+	// dest is the path of the copy relative to the copied resource
+	dest string
+	// rel is the slash-separated path relative to the worktree root
+	rel  string
+	info os.FileInfo
+	// target is the target of a symlink
+	target string
+}
+
+// copyTree copies the entries of tree, found by scanDir, to dest
+func (c *copier) copyTree(tree *copyTree, dest string) error {
+	c.progress.addFiles(tree.size())
+
+	// Parents come before their children. Directories stay writable until
+	// their content is copied.
+	for _, dir := range tree.dirs {
+		if err := os.MkdirAll(filepath.Join(dest, dir.dest), dir.info.Mode().Perm()|0700); err != nil {
+			return fmt.Errorf("failed to create destination directory: %w", err)
+		}
 	}
-	defer destFile.Close()
 
-	if _, err := io.Copy(destFile, sourceFile); err != nil {
-		return fmt.Errorf("failed to copy file: %w", err)
+	var wg gosync.WaitGroup
+	for _, file := range tree.files {
+		if c.failed() {
+			break
+		}
+		wg.Add(1)
+		c.files.run(func() {
+			defer wg.Done()
+			if err := c.copyFile(file.source, filepath.Join(dest, file.dest)); err != nil {
+				c.fail(err)
+				return
+			}
+			// Progress counts the size found by the scan, which files that
+			// grow while being copied would otherwise exceed
+			c.progress.fileDone(file.info.Size())
+		})
+	}
+	wg.Wait()
+	if c.err != nil {
+		return c.err
 	}
 
-	// Copy file permissions
-	sourceInfo, err := os.Stat(source)
-	if err != nil {
-		return fmt.Errorf("failed to stat source file: %w", err)
+	for _, link := range tree.symlinks {
+		linkPath := filepath.Join(dest, link.dest)
+		if err := os.Symlink(link.target, linkPath); err != nil {
+			return fmt.Errorf("failed to create symlink: %w", err)
+		}
+		if uid, gid, ok := fileOwner(link.info); ok && os.Geteuid() == 0 {
+			os.Lchown(linkPath, uid, gid)
+		}
+	}
+
+	for _, fifo := range tree.fifos {
+		fifoPath := filepath.Join(dest, fifo.dest)
+		if err := mkfifo(fifoPath, fifo.info.Mode()); err != nil {
+			c.special = append(c.special, fifo.rel)
+			continue
+		}
+		if err := copyMetadata(fifo.source, fifoPath, fifo.info); err != nil {
+			return err
+		}
 	}
 
-	if err := os.Chmod(dest, sourceInfo.Mode()); err != nil {
-		return fmt.Errorf("failed to set permissions: %w", err)
+	// Children come before their parents, whose modification times would
+	// otherwise change again
+	for i := len(tree.dirs) - 1; i >= 0; i-- {
+		dir := tree.dirs[i]
+		if err := copyMetadata(dir.source, filepath.Join(dest, dir.dest), dir.info); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func copyDir(source, dest string) error {
+// scanDir adds the entries below source to tree, skipping excluded entries.
+// Excluded directories are not descended into and symlinks are never
+// followed. dest is the path of source relative to the copy and rel its
+// slash-separated path relative to the worktree root, used for exclude
+// matching.
+func (c *copier) scanDir(source, dest, rel string, tree *copyTree) error {
 	// Get source directory info
 	sourceInfo, err := os.Stat(source)
 	if err != nil {
 		return fmt.Errorf("failed to stat source directory: %w", err)
 	}
-
-	// Create destination directory
-	if err := os.MkdirAll(dest, sourceInfo.Mode()); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
-	}
+	tree.dirs = append(tree.dirs, copyEntry{source: source, dest: dest, rel: rel, info: sourceInfo})
 
 	// Read directory entries
 	entries, err := os.ReadDir(source)
@@ -178,36 +779,197 @@ func copyDir(source, dest string) error {
 		return fmt.Errorf("failed to read directory: %w", err)
 	}
 
-	// Copy each entry
 	for _, entry := range entries {
 		sourcePath := filepath.Join(source, entry.Name())
 		destPath := filepath.Join(dest, entry.Name())
+		entryRel := rel + "/" + entry.Name()
+
+		if c.exclude.Match(entryRel) {
+			c.excluded = append(c.excluded, entryRel)
+			continue
+		}
 
 		if entry.IsDir() {
-			if err := copyDir(sourcePath, destPath); err != nil {
+			if err := c.scanDir(sourcePath, destPath, entryRel, tree); err != nil {
 				return err
 			}
-		} else {
-			if err := copyFile(sourcePath, destPath); err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat source file: %w", err)
+		}
+		copied := copyEntry{source: sourcePath, dest: destPath, rel: entryRel, info: info}
+
+		switch mode := info.Mode(); {
+		case mode.IsRegular():
+			tree.files = append(tree.files, copied)
+		case mode&os.ModeSymlink != 0:
+			if copied.target, err = c.linkTarget(copied); err != nil {
 				return err
 			}
+			tree.symlinks = append(tree.symlinks, copied)
+		case mode&os.ModeNamedPipe != 0:
+			tree.fifos = append(tree.fifos, copied)
+		default:
+			// Sockets and devices belong to the process or system that
+			// created them
+			c.special = append(c.special, entryRel)
 		}
 	}
 
 	return nil
 }
 
-// CheckSyncStatus checks if resources are synced in the destination
-func CheckSyncStatus(cfg *config.Config, sourceDir, destDir string) (bool, error) {
-	// Check all resources
-	allResources := append(cfg.Resources.Symlink, cfg.Resources.Copy...)
+// linkTarget returns the target of the copy of a symlink. Targets
+// within the worktree are kept as they are, so that relative links resolve
+// within the copy; relative targets outside the worktree are made absolute.
+func (c *copier) linkTarget(link copyEntry) (string, error) {
+	target, err := os.Readlink(link.source)
+	if err != nil {
+		return "", fmt.Errorf("failed to read symlink: %w", err)
+	}
+
+	if _, err := os.Stat(link.source); isLoop(err) {
+		c.loops = append(c.loops, link.rel)
+	}
+
+	if filepath.IsAbs(target) {
+		return target, nil
+	}
+	resolved := filepath.Join(filepath.Dir(link.source), target)
+	if isWithin(c.root, resolved) {
+		return target, nil
+	}
+	return resolved, nil
+}
+
+// warnings describes entries that were not copied faithfully
+func (c *copier) warnings() string {
+	var warnings []string
+	if len(c.special) > 0 {
+		warnings = append(warnings, fmt.Sprintf("skipped %d special files: %s", len(c.special), summarize(c.special)))
+	}
+	if len(c.loops) > 0 {
+		warnings = append(warnings, fmt.Sprintf("copied %d looping symlinks as they are: %s", len(c.loops), summarize(c.loops)))
+	}
+	return strings.Join(warnings, "; ")
+}
+
+// summarize lists the first few paths
+func summarize(paths []string) string {
+	const limit = 3
+	if len(paths) <= limit {
+		return strings.Join(paths, ", ")
+	}
+	return strings.Join(paths[:limit], ", ") + fmt.Sprintf(" and %d more", len(paths)-limit)
+}
+
+// fail records the first error of a file copy
+func (c *copier) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *copier) failed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err != nil
+}
 
+// ManagedSymlinks returns the configured resources in destDir that are
+// symlinks pointing into sourceDir
+func ManagedSymlinks(cfg *config.Config, sourceDir, destDir string) []string {
+	var managed []string
+
+	// Links are created from absolute paths, which may differ from sourceDir
+	// only by symlinks in its parents
+	sourceDirs := []string{sourceDir}
+	if resolved, err := filepath.EvalSymlinks(sourceDir); err == nil {
+		sourceDirs = append(sourceDirs, resolved)
+	}
+
+	var allResources []string
+	if resources, err := expandResources(cfg, sourceDir); err == nil {
+		allResources = append(resources.symlink, resources.copy...)
+	} else {
+		allResources = append(append([]string{}, cfg.Resources.Symlink...), cfg.Resources.Copy...)
+	}
 	for _, resource := range allResources {
 		destPath := filepath.Join(destDir, resource)
-		if _, err := os.Lstat(destPath); os.IsNotExist(err) {
-			return false, nil
+		link, err := os.Readlink(destPath)
+		if err != nil {
+			continue
+		}
+
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(destPath), link)
+		}
+		for _, dir := range sourceDirs {
+			if isWithin(dir, link) {
+				managed = append(managed, resource)
+				break
+			}
 		}
 	}
 
-	return true, nil
+	return managed
+}
+
+// UnlinkResources removes the managed symlinks from destDir without
+// following them. restore re-creates the links that were removed, for when
+// the worktree cannot be removed after all.
+func UnlinkResources(cfg *config.Config, sourceDir, destDir string) (unlinked []string, restore func() error, err error) {
+	targets := make(map[string]string)
+	restore = func() error {
+		for _, resource := range unlinked {
+			if err := os.Symlink(targets[resource], filepath.Join(destDir, resource)); err != nil {
+				return fmt.Errorf("failed to restore link %s: %w", resource, err)
+			}
+		}
+		return nil
+	}
+
+	for _, resource := range ManagedSymlinks(cfg, sourceDir, destDir) {
+		path := filepath.Join(destDir, resource)
+		target, err := os.Readlink(path)
+		if err != nil {
+			return unlinked, restore, fmt.Errorf("failed to read link %s: %w", resource, err)
+		}
+		// os.Remove on a symlink removes the link itself, never its target
+		if err := os.Remove(path); err != nil {
+			return unlinked, restore, fmt.Errorf("failed to unlink %s: %w", resource, err)
+		}
+		targets[resource] = target
+		unlinked = append(unlinked, resource)
+	}
+	return unlinked, restore, nil
+}
+
+// SameDir reports whether a and b are the same directory once symlinks are
+// resolved. Paths that do not exist are compared as they are.
+func SameDir(a, b string) bool {
+	resolve := func(path string) string {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return filepath.Clean(path)
+	}
+	return resolve(a) == resolve(b)
+}
+
+// isWithin reports whether path is dir or inside dir
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
 }
@@ -0,0 +1,257 @@
+package sync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+func writeFiles(t *testing.T, root string, files []string) {
+	t.Helper()
+	for _, file := range files {
+		filePath := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(file), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+}
+
+func TestSyncResourcesExclude(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	writeFiles(t, sourceDir, []string{
+		"config/app.yml",
+		"config/debug.log",
+		"config/keep.log",
+		"config/cache/data.bin",
+		"config/nested/trace.log",
+	})
+
+	cfg := &config.Config{
+		Resources: config.Resources{Copy: []string{"config"}},
+		Exclude:   []string{"*.log", "!keep.log", "config/cache"},
+	}
+
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("expected one successful result, got %+v", results)
+	}
+	if results[0].Excluded != 3 {
+		t.Errorf("expected 3 excluded entries, got %d", results[0].Excluded)
+	}
+
+	expected := map[string]bool{
+		"config/app.yml":          true,
+		"config/keep.log":         true,
+		"config/debug.log":        false,
+		"config/cache":            false,
+		"config/nested/trace.log": false,
+	}
+	for file, exists := range expected {
+		_, err := os.Lstat(filepath.Join(destDir, file))
+		if exists && err != nil {
+			t.Errorf("expected %s to be copied", file)
+		}
+		if !exists && err == nil {
+			t.Errorf("expected %s to be excluded", file)
+		}
+	}
+}
+
+func TestSyncResourcesPatterns(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	writeFiles(t, sourceDir, []string{
+		"node_modules/dep/index.js",
+		"node_modules/dep/node_modules/nested/index.js",
+		"packages/a/node_modules/dep/index.js",
+		"packages/b/node_modules/dep/index.js",
+		"packages/a/.env",
+		"packages/b/.env",
+	})
+
+	cfg := &config.Config{
+		Resources: config.Resources{
+			Symlink: []string{"**/node_modules", "packages/a/node_modules"},
+			Copy:    []string{"packages/*/.env", "**/dist"},
+		},
+	}
+
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	modes := make(map[string]string)
+	for _, result := range results {
+		modes[result.Resource] = result.Mode
+	}
+	expected := map[string]string{
+		"**/dist":                 "skip",
+		"node_modules":            "symlink",
+		"packages/a/node_modules": "symlink",
+		"packages/b/node_modules": "symlink",
+		"packages/a/.env":         "copy",
+		"packages/b/.env":         "copy",
+	}
+	if len(modes) != len(expected) {
+		t.Errorf("expected %d results, got %+v", len(expected), results)
+	}
+	for resource, mode := range expected {
+		if modes[resource] != mode {
+			t.Errorf("expected %s to be %s, got %q", resource, mode, modes[resource])
+		}
+	}
+
+	if !isLinkTo(filepath.Join(destDir, "packages/b/node_modules"), filepath.Join(sourceDir, "packages/b/node_modules")) {
+		t.Error("expected packages/b/node_modules to link to the source")
+	}
+
+	report, err := CheckSyncStatus(cfg, sourceDir, destDir, false)
+	if err != nil {
+		t.Fatalf("failed to check status: %v", err)
+	}
+	if len(report.Resources) != 5 {
+		t.Errorf("expected 5 resources in status, got %+v", report.Resources)
+	}
+}
+
+func TestSyncResourcesForce(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	if output, err := exec.Command("git", "init", destDir).CombinedOutput(); err != nil {
+		t.Fatalf("failed to init repository: %v\n%s", err, output)
+	}
+
+	writeFiles(t, sourceDir, []string{".env"})
+	if err := os.WriteFile(filepath.Join(destDir, ".env"), []byte("local"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cfg := &config.Config{
+		Resources: config.Resources{Copy: []string{".env"}},
+	}
+
+	// Without force the existing destination is kept
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if results[0].Mode != "exists" {
+		t.Errorf("expected mode exists, got %s", results[0].Mode)
+	}
+
+	results, err = SyncResources(cfg, sourceDir, destDir, Options{Force: true})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if results[0].Mode != "replaced" || !results[0].Success {
+		t.Fatalf("expected successful replace, got %+v", results[0])
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, ".env"))
+	if err != nil || string(data) != ".env" {
+		t.Errorf("expected destination to be replaced, got %q (%v)", data, err)
+	}
+
+	backup, err := os.ReadFile(results[0].Backup)
+	if err != nil || string(backup) != "local" {
+		t.Errorf("expected backup to hold previous content, got %q (%v)", backup, err)
+	}
+	if !strings.Contains(filepath.ToSlash(results[0].Backup), ".git/"+BackupDirName+"/") {
+		t.Errorf("expected backup inside git dir, got %s", results[0].Backup)
+	}
+
+	// The replaced resource is managed like any other copy
+	report, err := CheckSyncStatus(cfg, sourceDir, destDir, false)
+	if err != nil {
+		t.Fatalf("failed to check status: %v", err)
+	}
+	if status := report.Resources[0]; status.State != StateSynced {
+		t.Errorf("expected replaced resource to be synced, got %s (%s)", status.State, status.Detail)
+	}
+}
+
+func TestSyncResourcesSameWorktree(t *testing.T) {
+	root := t.TempDir()
+	sourceDir := filepath.Join(root, "main")
+	writeFiles(t, sourceDir, []string{".env", "node_modules/pkg/index.js"})
+	// The destination is the source reached through a symlink
+	destDir := filepath.Join(root, "alias")
+	if err := os.Symlink(sourceDir, destDir); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Resources: config.Resources{Symlink: []string{"node_modules"}, Copy: []string{".env"}},
+	}
+	if _, err := SyncResources(cfg, sourceDir, destDir, Options{Force: true}); err == nil {
+		t.Error("expected sync into the source worktree to be refused")
+	}
+	if _, err := PlanSync(cfg, sourceDir, destDir+"/", Options{Force: true}); err == nil {
+		t.Error("expected plan for the source worktree to be refused")
+	}
+
+	if info, err := os.Lstat(filepath.Join(sourceDir, "node_modules")); err != nil || !info.IsDir() {
+		t.Errorf("expected node_modules to be left alone, got %v", err)
+	}
+}
+
+func TestUnlinkResources(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	writeFiles(t, sourceDir, []string{"node_modules/pkg/index.js", "vendor/lib.rb"})
+	writeFiles(t, destDir, []string{"vendor/local.rb"})
+
+	cfg := &config.Config{
+		Resources: config.Resources{Symlink: []string{"node_modules", "vendor"}},
+	}
+
+	if _, err := SyncResources(cfg, sourceDir, destDir, Options{}); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	managed := ManagedSymlinks(cfg, sourceDir, destDir)
+	if len(managed) != 1 || managed[0] != "node_modules" {
+		t.Fatalf("expected only node_modules to be managed, got %v", managed)
+	}
+
+	unlinked, restore, err := UnlinkResources(cfg, sourceDir, destDir)
+	if err != nil {
+		t.Fatalf("failed to unlink: %v", err)
+	}
+	if len(unlinked) != 1 {
+		t.Errorf("expected 1 unlinked resource, got %v", unlinked)
+	}
+
+	if _, err := os.Lstat(filepath.Join(destDir, "node_modules")); !os.IsNotExist(err) {
+		t.Error("expected symlink to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(sourceDir, "node_modules", "pkg", "index.js")); err != nil {
+		t.Error("expected link target to be untouched")
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "vendor", "local.rb")); err != nil {
+		t.Error("expected unmanaged directory to be untouched")
+	}
+
+	if err := restore(); err != nil {
+		t.Fatalf("failed to restore links: %v", err)
+	}
+	if managed := ManagedSymlinks(cfg, sourceDir, destDir); len(managed) != 1 || managed[0] != "node_modules" {
+		t.Errorf("expected node_modules to be linked again, got %v", managed)
+	}
+}
@@ -0,0 +1,215 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+	"syscall"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+// Transaction records the changes SyncResources makes to a worktree so that
+// they can be undone, leaving the worktree as it was before the sync
+type Transaction struct {
+	mu    gosync.Mutex
+	steps []undoStep
+}
+
+type undoStep struct {
+	resource    string
+	description string
+	undo        func() error
+}
+
+// RollbackStep is the outcome of undoing a single change
+type RollbackStep struct {
+	// Resource is the resource the change belongs to, empty for changes
+	// such as the manifest that belong to the whole sync
+	Resource    string
+	Description string
+	Error       error
+}
+
+// NewTransaction returns an empty transaction
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+// record adds a change and the function that undoes it
+func (t *Transaction) record(resource, description string, undo func() error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, undoStep{resource: resource, description: description, undo: undo})
+}
+
+// Empty reports whether no changes were recorded
+func (t *Transaction) Empty() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.steps) == 0
+}
+
+// Rollback undoes the recorded changes, most recent first, and returns the
+// outcome of every step. Steps that fail do not stop the rollback.
+func (t *Transaction) Rollback() []RollbackStep {
+	t.mu.Lock()
+	steps := t.steps
+	t.steps = nil
+	t.mu.Unlock()
+
+	report := make([]RollbackStep, 0, len(steps))
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		report = append(report, RollbackStep{
+			Resource:    step.resource,
+			Description: step.description,
+			Error:       step.undo(),
+		})
+	}
+	return report
+}
+
+// RollbackError is returned by SyncResources in atomic mode when a resource
+// fails to sync and the changes made so far are rolled back
+type RollbackError struct {
+	// Resource is the first resource that failed
+	Resource string
+	Err      error
+	Steps    []RollbackStep
+}
+
+func (e *RollbackError) Error() string {
+	var failed []string
+	for _, step := range e.Steps {
+		if step.Error != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", step.Description, step.Error))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Sprintf("failed to sync %s: %v; rollback incomplete: %s", e.Resource, e.Err, strings.Join(failed, "; "))
+	}
+	return fmt.Sprintf("failed to sync %s: %v; all changes were rolled back", e.Resource, e.Err)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// removeAll removes path and everything below it, including the content
+// of read-only directories
+func removeAll(path string) error {
+	if err := os.RemoveAll(path); err == nil {
+		return nil
+	}
+
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				os.Chmod(p, info.Mode().Perm()|0700)
+			}
+		}
+		return nil
+	})
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// moveTree renames source to dest. Backups live in the git directory,
+// which may be on another filesystem than the worktree; there rename fails
+// with EXDEV, so source is copied and then removed instead.
+func moveTree(source, dest string) error {
+	err := os.Rename(source, dest)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	return moveByCopy(source, dest)
+}
+
+// moveByCopy copies source to dest as faithfully as a sync copies a
+// resource, then removes source
+func moveByCopy(source, dest string) error {
+	info, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+
+	files := newFilePool(1)
+	defer files.close()
+	c := &copier{
+		// Link targets are kept exactly as they are, as a rename would
+		root:     filepath.VolumeName(source) + string(filepath.Separator),
+		strategy: config.CopyStrategyAuto,
+		files:    files,
+		progress: newProgressTracker(),
+	}
+
+	tree := &copyTree{}
+	entry := copyEntry{source: source, dest: ".", rel: filepath.Base(source), info: info}
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		err = c.scanDir(source, ".", entry.rel, tree)
+	case mode.IsRegular():
+		tree.files = append(tree.files, entry)
+	case mode&os.ModeSymlink != 0:
+		entry.target, err = c.linkTarget(entry)
+		tree.symlinks = append(tree.symlinks, entry)
+	case mode&os.ModeNamedPipe != 0:
+		tree.fifos = append(tree.fifos, entry)
+	default:
+		return fmt.Errorf("cannot move special file %s", source)
+	}
+	if err == nil {
+		err = c.copyTree(tree, dest)
+	}
+	if err != nil {
+		removeAll(dest)
+		return fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+
+	return removeAll(source)
+}
+
+// removeEmptyDirs removes dir and the directories below it, keeping any
+// directory that still holds files
+func removeEmptyDirs(dir string) {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, entry.Name()))
+		}
+	}
+	// Fails, leaving dir in place, if it is not empty
+	os.Remove(dir)
+}
+
+// rollback undoes the changes of a failed atomic sync and marks the results
+// of the resources whose changes were undone
+func (s *syncer) rollback(results []SyncResult) error {
+	var failed SyncResult
+	for _, result := range results {
+		if result.Failed() {
+			failed = result
+			break
+		}
+	}
+
+	steps := s.tx.Rollback()
+	undone := make(map[string]bool)
+	for _, step := range steps {
+		undone[step.Resource] = true
+	}
+	for i := range results {
+		if results[i].Success && undone[results[i].Resource] {
+			results[i].RolledBack = true
+		}
+	}
+
+	return &RollbackError{Resource: failed.Resource, Err: failed.Error, Steps: steps}
+}
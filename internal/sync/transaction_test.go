@@ -0,0 +1,224 @@
+package sync
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fs0414/git-worktree-sync/internal/config"
+)
+
+// snapshotTree returns the files, directories and symlinks below dir with
+// their content or target
+func snapshotTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			tree[rel] = "-> " + target
+			return err
+		case d.IsDir():
+			tree[rel] = "dir"
+		default:
+			data, err := os.ReadFile(path)
+			tree[rel] = string(data)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to snapshot %s: %v", dir, err)
+	}
+	return tree
+}
+
+// newWorktree returns a source directory and a git repository to sync into
+func newWorktree(t *testing.T) (string, string) {
+	t.Helper()
+	sourceDir, destDir := t.TempDir(), t.TempDir()
+	if output, err := exec.Command("git", "init", destDir).CombinedOutput(); err != nil {
+		t.Fatalf("failed to init repository: %v\n%s", err, output)
+	}
+	return sourceDir, destDir
+}
+
+func TestSyncResourcesAtomic(t *testing.T) {
+	sourceDir, destDir := newWorktree(t)
+	writeFiles(t, sourceDir, []string{"node_modules/dep/index.js", ".env", "config/app.yml", "data/db.sqlite"})
+	writeFiles(t, destDir, []string{".env"})
+	// config cannot be created because a file is in the way
+	if err := os.WriteFile(filepath.Join(destDir, "config"), []byte("not a directory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Resources: config.Resources{
+			Symlink: []string{"node_modules"},
+			Copy:    []string{".env", "config/app.yml", "data"},
+		},
+	}
+
+	// A successful sync leaves a manifest that must survive the rollback
+	if _, err := SyncResources(&config.Config{}, sourceDir, destDir, Options{}); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	before := snapshotTree(t, destDir)
+
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{Force: true, Atomic: true, Jobs: 1})
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if rollbackErr.Resource != "config/app.yml" || !strings.Contains(err.Error(), "all changes were rolled back") {
+		t.Errorf("unexpected rollback error: %v", err)
+	}
+
+	modes := make(map[string]string)
+	for _, result := range results {
+		modes[result.Resource] = result.Mode
+		if result.Success && !result.RolledBack {
+			t.Errorf("expected %s to be rolled back", result.Resource)
+		}
+	}
+	want := map[string]string{"node_modules": "symlink", ".env": "replaced", "config/app.yml": "error", "data": "cancelled"}
+	if !reflect.DeepEqual(modes, want) {
+		t.Errorf("expected modes %v, got %v", want, modes)
+	}
+
+	if after := snapshotTree(t, destDir); !reflect.DeepEqual(before, after) {
+		t.Errorf("expected worktree to be unchanged\nbefore: %v\nafter:  %v", before, after)
+	}
+}
+
+func TestSyncResourcesStaged(t *testing.T) {
+	sourceDir, destDir := t.TempDir(), t.TempDir()
+	writeFiles(t, sourceDir, []string{"build/assets/app.js", "build/assets/app.css"})
+	// A leftover from an interrupted sync
+	if err := os.MkdirAll(filepath.Join(destDir, stagePrefix+"build-123/build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reflinks fail on most filesystems once the directories were created
+	cfg := &config.Config{
+		Resources: config.Resources{Copy: []string{"build"}},
+		ResourceOptions: map[string]config.ResourceOptions{
+			"build": {CopyStrategy: config.CopyStrategyReflink},
+		},
+	}
+	results, err := SyncResources(cfg, sourceDir, destDir, Options{})
+	if err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if results[0].Success {
+		t.Skip("reflinks are supported here, so the copy cannot be made to fail")
+	}
+
+	if _, err := os.Lstat(filepath.Join(destDir, "build")); err == nil {
+		t.Error("expected no partial copy at the destination")
+	}
+	entries, _ := os.ReadDir(destDir)
+	for _, entry := range entries {
+		t.Errorf("expected %s to be removed", entry.Name())
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	sourceDir, destDir := newWorktree(t)
+	writeFiles(t, sourceDir, []string{"node_modules/dep/index.js", "packages/a/.env", "vendor/gem.rb", "Gemfile.lock"})
+	writeFiles(t, destDir, []string{"Gemfile.lock"})
+	if err := os.Symlink(filepath.Join(sourceDir, "vendor"), filepath.Join(destDir, "vendor")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(destDir, "Gemfile.lock"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := snapshotTree(t, destDir)
+
+	cfg := &config.Config{
+		Resources: config.Resources{
+			Symlink: []string{"node_modules", "vendor"},
+			Copy:    []string{"packages/a/.env"},
+		},
+		ResourceOptions: map[string]config.ResourceOptions{
+			"vendor": {Lockfiles: []string{"Gemfile.lock"}, OnLockfileChange: config.LockfileCopy},
+		},
+	}
+
+	tx := NewTransaction()
+	if _, err := SyncResources(cfg, sourceDir, destDir, Options{Transaction: tx}); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "packages/a/.env")); err != nil {
+		t.Fatalf("expected .env to be copied: %v", err)
+	}
+
+	var descriptions []string
+	for _, step := range tx.Rollback() {
+		if step.Error != nil {
+			t.Errorf("step %q failed: %v", step.Description, step.Error)
+		}
+		descriptions = append(descriptions, step.Description)
+	}
+	for _, want := range []string{"Removed node_modules", "Relinked vendor", "Removed directory packages", "Restored sync manifest"} {
+		if !strings.Contains(strings.Join(descriptions, "\n"), want) {
+			t.Errorf("expected step %q, got %v", want, descriptions)
+		}
+	}
+
+	if after := snapshotTree(t, destDir); !reflect.DeepEqual(before, after) {
+		t.Errorf("expected worktree to be unchanged\nbefore: %v\nafter:  %v", before, after)
+	}
+	if !tx.Empty() {
+		t.Error("expected rolled back transaction to be empty")
+	}
+}
+
+func TestMoveByCopy(t *testing.T) {
+	sourceDir, destDir := t.TempDir(), t.TempDir()
+	writeFiles(t, sourceDir, []string{"node_modules/dep/index.js", ".env"})
+	if err := os.Symlink("../../shared", filepath.Join(sourceDir, "node_modules/shared")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("node_modules", filepath.Join(sourceDir, "modules")); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(sourceDir, ".env"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rename across filesystems falls back to this
+	for _, name := range []string{"node_modules", ".env", "modules"} {
+		if err := moveByCopy(filepath.Join(sourceDir, name), filepath.Join(destDir, name)); err != nil {
+			t.Fatalf("failed to move %s: %v", name, err)
+		}
+		if _, err := os.Lstat(filepath.Join(sourceDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed from the source, got %v", name, err)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(destDir, "node_modules/dep/index.js")); err != nil || string(data) != "node_modules/dep/index.js" {
+		t.Errorf("expected the file to be moved, got %q (%v)", data, err)
+	}
+	// Link targets are kept as they were, even relative ones leaving the tree
+	for link, want := range map[string]string{"node_modules/shared": "../../shared", "modules": "node_modules"} {
+		if target, err := os.Readlink(filepath.Join(destDir, link)); err != nil || target != want {
+			t.Errorf("expected %s -> %s, got %q (%v)", link, want, target, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(destDir, ".env")); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("expected the modification time to be kept, got %v", info)
+	}
+}